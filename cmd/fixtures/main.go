@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"baia/internal/scraper"
+	"baia/internal/scrapertest"
+)
+
+const usage = `Usage: fixtures <verify|update|record> [case directories...]
+
+  verify  runs every case against its recorded pages and compares the result
+          with its golden file, without touching the network
+  update  rewrites the golden file of every case from its recorded pages
+  record  fetches the pages of every case again and rewrites its golden file
+
+Without directories, every case below internal/scraper is used.
+`
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "verify" && os.Args[1] != "update" && os.Args[1] != "record") {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	registry := scraper.NewRegistry()
+	ctx := context.Background()
+
+	dirs := os.Args[2:]
+	if len(dirs) == 0 {
+		var err error
+		dirs, err = scrapertest.Cases("internal/scraper")
+		if err != nil {
+			log.Fatalf("Failed to find fixture cases: %v", err)
+		}
+	}
+
+	failed := 0
+
+	for _, dir := range dirs {
+		switch os.Args[1] {
+		case "record":
+			if err := scrapertest.Record(ctx, logger, registry, dir); err != nil {
+				fmt.Printf("FAIL %s: %v\n", dir, err)
+				failed++
+				continue
+			}
+			fmt.Printf("recorded %s\n", dir)
+		case "update":
+			if err := scrapertest.Update(ctx, logger, registry, dir); err != nil {
+				fmt.Printf("FAIL %s: %v\n", dir, err)
+				failed++
+				continue
+			}
+			fmt.Printf("updated  %s\n", dir)
+		case "verify":
+			diff, err := scrapertest.Verify(ctx, logger, registry, dir)
+			if err != nil {
+				fmt.Printf("FAIL %s: %v\n", dir, err)
+				failed++
+				continue
+			}
+			if diff != "" {
+				fmt.Printf("FAIL %s: result differs from %s\n%s", dir, scrapertest.GoldenFile, diff)
+				failed++
+				continue
+			}
+			fmt.Printf("ok   %s\n", dir)
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
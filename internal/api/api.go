@@ -0,0 +1,162 @@
+// Package api serves the real estates of a repository as read-only JSON.
+//
+//	GET /real-estates              search, filtered by the query parameters of SearchFilter
+//	GET /real-estates/{id}         one real estate
+//	GET /real-estates/{id}/prices  its price history
+//	GET /stats                     real estates per agency
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"baia/internal/contracts"
+)
+
+// Server answers the API requests from a repository.
+type Server struct {
+	repo   contracts.RealEstateRepository
+	logger *slog.Logger
+	mux    *http.ServeMux
+}
+
+// NewServer is a constructor that creates a new Server with its routes.
+func NewServer(repo contracts.RealEstateRepository, logger *slog.Logger) *Server {
+	s := &Server{
+		repo:   repo,
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /real-estates", s.search)
+	s.mux.HandleFunc("GET /real-estates/{id}", s.get)
+	s.mux.HandleFunc("GET /real-estates/{id}/prices", s.prices)
+	s.mux.HandleFunc("GET /stats", s.stats)
+
+	return s
+}
+
+// Handle adds a route next to the ones of the API.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err)
+		return
+	}
+
+	list, err := s.repo.Search(r.Context(), filter)
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.json(w, list)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	realEstate, err := s.repo.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, contracts.ErrNotFound) {
+		s.error(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.json(w, realEstate)
+}
+
+func (s *Server) prices(w http.ResponseWriter, r *http.Request) {
+	history, err := s.repo.PriceHistory(r.Context(), r.PathValue("id"))
+	if errors.Is(err, contracts.ErrNotFound) {
+		s.error(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.json(w, history)
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.repo.Stats(r.Context())
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.json(w, stats)
+}
+
+// parseFilter reads a search filter from query parameters named after its fields.
+func parseFilter(query url.Values) (contracts.SearchFilter, error) {
+	filter := contracts.SearchFilter{
+		Agency:      query.Get("agency"),
+		City:        query.Get("city"),
+		Type:        query.Get("type"),
+		Transaction: query.Get("transaction"),
+	}
+
+	ints := map[string]*int{
+		"minPrice":    &filter.MinPrice,
+		"maxPrice":    &filter.MaxPrice,
+		"minBedrooms": &filter.MinBedrooms,
+		"limit":       &filter.Limit,
+		"offset":      &filter.Offset,
+	}
+
+	for name, dst := range ints {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return filter, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*dst = n
+		}
+	}
+
+	if value := query.Get("includeDelisted"); value != "" {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid includeDelisted %q", value)
+		}
+		filter.IncludeDelisted = b
+	}
+
+	return filter, nil
+}
+
+func (s *Server) json(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Warn("Failed to write response", "error", err)
+	}
+}
+
+func (s *Server) error(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		s.logger.Error("Request failed", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
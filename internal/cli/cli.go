@@ -0,0 +1,513 @@
+// Package cli implements the baia command line: one subcommand per task, each
+// with its own flags on top of the common ones.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/metrics"
+	"baia/internal/scraper"
+	"baia/internal/scraper/trace"
+	"baia/internal/utils"
+	"baia/pkg/collector"
+	"baia/pkg/database"
+
+	"github.com/joho/godotenv"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/ricardocastanho/scrapify"
+)
+
+// Exit codes of the baia command.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+	// ExitInterrupted follows the shell convention for processes stopped by SIGINT.
+	ExitInterrupted = 130
+)
+
+// DefaultGrace is how long work in flight may run after a shutdown signal.
+const DefaultGrace = time.Second * 30
+
+// DefaultEnvFile is the environment file loaded when --env-file is not set.
+const DefaultEnvFile = ".env"
+
+// DefaultCacheTTL and DefaultCacheSize, in MB, bound the page cache when the
+// flags do not.
+const (
+	DefaultCacheTTL  = time.Hour * 24
+	DefaultCacheSize = 512
+)
+
+// command is a subcommand, holding the values of its own flags.
+type command interface {
+	// flags registers the flags of the command.
+	flags(fs *flag.FlagSet)
+	// run executes the command with the arguments left after the flags.
+	run(ctx context.Context, app *App, args []string) error
+}
+
+// commandSpec describes a subcommand and the common flags it accepts.
+type commandSpec struct {
+	name    string
+	args    string
+	summary string
+	// timeout is the default of --timeout, zero meaning no timeout.
+	timeout time.Duration
+	// sources tells whether the command reads the sources file.
+	sources bool
+	new     func() command
+}
+
+var commands = []commandSpec{
+	{name: "scrape", summary: "scrape the configured sources into the graph", timeout: time.Minute * 45, sources: true, new: func() command { return &scrapeCommand{} }},
+	{name: "scrape-url", args: "<url>", summary: "scrape one detail page and print it, without saving", timeout: time.Minute, sources: true, new: func() command { return &scrapeUrlCommand{} }},
+	{name: "debug", args: "<url>", summary: "scrape one detail page and report how each field was extracted", timeout: time.Minute, sources: true, new: func() command { return &debugCommand{} }},
+	{name: "export", args: "[output]", summary: "scrape the sources into a JSON Lines file, without Neo4j", timeout: time.Minute * 45, sources: true, new: func() command { return &exportCommand{} }},
+	{name: "daemon", summary: "scrape every source on its schedule and serve the graph", sources: true, new: func() command { return &daemonCommand{} }},
+	{name: "import", args: "<dump>...", summary: "replay JSON Lines or CSV dumps into the graph", new: func() command { return &importCommand{} }},
+	{name: "migrate", summary: "apply the pending database migrations", timeout: time.Minute * 10, new: func() command { return &migrateCommand{} }},
+	{name: "serve", summary: "serve the real estates of the graph as JSON over HTTP", new: func() command { return &serveCommand{} }},
+	{name: "quarantine", args: "list|inspect|reprocess [id]...", summary: "list, inspect and reprocess the listings held back as invalid", timeout: time.Minute * 45, sources: true, new: func() command { return &quarantineCommand{} }},
+	{name: "stats", summary: "print how many real estates each agency has", timeout: time.Minute, sources: true, new: func() command { return &statsCommand{} }},
+}
+
+// usageError is returned for invalid command lines, which exit with ExitUsage.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// stringList is a flag that can be repeated or given comma separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// options holds the common flags.
+type options struct {
+	timeout     time.Duration
+	grace       time.Duration
+	logLevel    string
+	envFile     string
+	sourcesFile string
+	sources     stringList
+	cacheDir    string
+	cacheTTL    time.Duration
+	cacheSize   int64
+	offline     bool
+}
+
+// App is what the commands share: the logger and access to the sources file
+// and the database, as set by the common flags and the environment.
+type App struct {
+	Logger   *slog.Logger
+	Stdout   io.Writer
+	Registry *scraper.Registry
+	// Work outlives the context given to the command by the grace period that
+	// follows a shutdown signal, for the work already in flight.
+	Work    context.Context
+	options options
+	// policy is shared by the collectors of every source of the sources file,
+	// failures counts per source the pages they could not fetch and changes
+	// tracks per source the pages that did not change.
+	policy   *collector.Policy
+	failures map[string]*collector.Failures
+	changes  map[string]*collector.Changes
+	// metrics are served by the daemon.
+	metrics *metrics.Metrics
+	// steps keeps per source what the fields of every detail page were
+	// extracted from, until the listing of the page is handled.
+	steps map[string]*trace.Pages
+}
+
+// interruptedError is returned by commands stopped by a signal.
+type interruptedError struct {
+	err error
+}
+
+func (e *interruptedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *interruptedError) Unwrap() error {
+	return e.err
+}
+
+// Run executes the command line, without the program name, and returns the
+// exit code. Without a command, scrape is run.
+func Run(args []string) int {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		printUsage(os.Stdout)
+		return ExitOK
+	}
+
+	name := "scrape"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	spec, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "baia: unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return ExitUsage
+	}
+
+	err := execute(spec, args)
+
+	var (
+		usage       *usageError
+		interrupted *interruptedError
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "baia %s: %v\nRun 'baia %s -h' for usage.\n", spec.name, err, spec.name)
+		return ExitUsage
+	case errors.As(err, &interrupted):
+		fmt.Fprintf(os.Stderr, "baia %s: %v\n", spec.name, err)
+		return ExitInterrupted
+	default:
+		fmt.Fprintf(os.Stderr, "baia %s: %v\n", spec.name, err)
+		return ExitFailure
+	}
+}
+
+func findCommand(name string) (commandSpec, bool) {
+	for _, spec := range commands {
+		if spec.name == name {
+			return spec, true
+		}
+	}
+	return commandSpec{}, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: baia <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, spec := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", spec.name, spec.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'baia <command> -h' for the flags of a command.")
+}
+
+// execute parses the flags of the command, prepares the app and runs it.
+func execute(spec commandSpec, args []string) error {
+	cmd := spec.new()
+	app := &App{Stdout: os.Stdout, Registry: scraper.NewRegistry(), metrics: metrics.New()}
+
+	fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	fs.DurationVar(&app.options.timeout, "timeout", spec.timeout, "stop after this long, 0 for no limit")
+	fs.DurationVar(&app.options.grace, "grace", DefaultGrace, "how long work in flight may run after SIGINT or SIGTERM")
+	fs.StringVar(&app.options.logLevel, "log-level", "info", "minimum level of the logs: debug, info, warn or error")
+	fs.StringVar(&app.options.envFile, "env-file", DefaultEnvFile, "file with the environment variables to load")
+	if spec.sources {
+		fs.StringVar(&app.options.sourcesFile, "sources-file", "", "sources file, defaults to $BAIA_SOURCES or sources.yaml")
+		fs.Var(&app.options.sources, "source", "only use the named sources, repeatable or comma separated")
+		fs.StringVar(&app.options.cacheDir, "cache-dir", "", "cache the fetched pages in this directory, defaults to $BAIA_CACHE_DIR or no cache")
+		fs.DurationVar(&app.options.cacheTTL, "cache-ttl", DefaultCacheTTL, "how long a cached page is served, 0 for ever")
+		fs.Int64Var(&app.options.cacheSize, "cache-max-mb", DefaultCacheSize, "size of the cache in MB above which the oldest pages are evicted, 0 for no limit")
+		fs.BoolVar(&app.options.offline, "offline", false, "serve every page from the cache, failing on the pages it does not have")
+	}
+	cmd.flags(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printCommandUsage(os.Stdout, spec, fs)
+			return err
+		}
+		return usagef("%v", err)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(app.options.logLevel)); err != nil {
+		return usagef("invalid log level %q", app.options.logLevel)
+	}
+
+	// Standard output is left to the results of the commands.
+	app.Logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	if err := app.loadEnv(fs); err != nil {
+		return err
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if app.options.timeout > 0 {
+		ctx, cancel = utils.NewTimeoutContext(app.options.timeout)
+	} else {
+		ctx, cancel = utils.NewCancelableContext()
+	}
+	defer cancel()
+
+	shutdown, release := utils.NewShutdown(ctx, app.options.grace, func(sig os.Signal, abandoning bool) {
+		switch {
+		case abandoning:
+			app.Logger.Warn("Abandoning work in flight", "signal", sig)
+		case sig == nil:
+			app.Logger.Warn("Timed out, finishing work in flight", "timeout", app.options.timeout.String(), "grace", app.options.grace.String())
+		default:
+			app.Logger.Warn("Shutting down, finishing work in flight", "signal", sig, "grace", app.options.grace.String())
+		}
+	})
+	defer release()
+
+	app.Work = shutdown.Work
+
+	err := cmd.run(shutdown.Stop, app, fs.Args())
+	if err != nil && shutdown.Signal() != nil {
+		return &interruptedError{err: err}
+	}
+
+	return err
+}
+
+// Grace returns a context bounded by the shutdown grace period, for the writes
+// that must still happen once the context of the command is done.
+func (a *App) Grace() (context.Context, context.CancelFunc) {
+	return utils.NewTimeoutContext(a.options.grace)
+}
+
+func printCommandUsage(w io.Writer, spec commandSpec, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: baia %s [flags] %s\n\n%s.\n\nFlags:\n", spec.name, spec.args, strings.ToUpper(spec.summary[:1])+spec.summary[1:])
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// loadEnv loads the environment file. The default one is optional, but a file
+// set with --env-file must exist.
+func (a *App) loadEnv(fs *flag.FlagSet) error {
+	explicit := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "env-file" {
+			explicit = true
+		}
+	})
+
+	err := godotenv.Load(a.options.envFile)
+	if err != nil && (explicit || !errors.Is(err, os.ErrNotExist)) {
+		return fmt.Errorf("failed to load environment file %s: %w", a.options.envFile, err)
+	}
+
+	return nil
+}
+
+// Sources loads the sources file, keeping only the sources named by --source.
+func (a *App) Sources() (*config.Config, error) {
+	path := a.options.sourcesFile
+	if path == "" {
+		path = os.Getenv("BAIA_SOURCES")
+	}
+	if path == "" {
+		path = "sources.yaml"
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sources file %s: %w", path, err)
+	}
+
+	if err := cfg.Select(a.options.sources); err != nil {
+		return nil, usagef("%v", err)
+	}
+
+	cache, err := a.cache()
+	if err != nil {
+		return nil, err
+	}
+
+	a.policy, err = collector.NewPolicy(a.Logger, cfg.Crawler.UserAgent, cfg.Crawler.RetryPolicy(), cache, cfg.Crawler.LimitRules()...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sources file %s: %w", path, err)
+	}
+
+	a.failures = make(map[string]*collector.Failures)
+	a.changes = make(map[string]*collector.Changes)
+	a.steps = make(map[string]*trace.Pages)
+	for _, source := range cfg.Sources {
+		a.failures[source.Name] = &collector.Failures{}
+		a.changes[source.Name] = collector.NewChanges()
+		a.steps[source.Name] = trace.NewPages()
+	}
+
+	return cfg, nil
+}
+
+// cache opens the page cache set by the flags, if any.
+func (a *App) cache() (*collector.Cache, error) {
+	dir := a.options.cacheDir
+	if dir == "" {
+		dir = os.Getenv("BAIA_CACHE_DIR")
+	}
+
+	if dir == "" {
+		if a.options.offline {
+			return nil, usagef("--offline needs a cache, set --cache-dir or BAIA_CACHE_DIR")
+		}
+		return nil, nil
+	}
+
+	if a.options.offline {
+		a.Logger.Info("Serving every page from the cache", "dir", dir)
+	}
+
+	return collector.NewCache(dir, a.options.cacheTTL, a.options.cacheSize<<20, a.options.offline)
+}
+
+// CollectorOptions returns the options of the collectors of the source, which
+// apply the crawler settings of the sources file.
+func (a *App) CollectorOptions(source config.Source) []collector.Option {
+	if a.policy == nil {
+		return nil
+	}
+
+	return a.policy.Options(collector.SourceOptions{
+		Logger:          a.Logger.With("source", source.Name),
+		IgnoreRobotsTxt: source.IgnoreRobotsTxt,
+		Failures:        a.failures[source.Name],
+		Changes:         a.changes[source.Name],
+	})
+}
+
+// Changes returns the tracker of the pages of the source that did not change,
+// which knows no page until it is reset with the ones fetched before.
+func (a *App) Changes(source string) *collector.Changes {
+	return a.changes[source]
+}
+
+// Steps returns what the fields of the detail pages of the source were
+// extracted from, which is kept until taken or reset.
+func (a *App) Steps(source string) *trace.Pages {
+	return a.steps[source]
+}
+
+// FetchFailures returns the pages of the source that could not be fetched
+// since the last call.
+func (a *App) FetchFailures(source string) collector.FailedPages {
+	if a.failures[source] == nil {
+		return nil
+	}
+
+	return a.failures[source].Take()
+}
+
+// Strategies builds the scraping strategies of every source, which report to
+// the metrics of the app and keep the raw text of the fields of every page.
+func (a *App) Strategies(cfg *config.Config) (map[string][]scrapify.ScraperStrategy[contracts.RealEstate], error) {
+	strategies := make(map[string][]scrapify.ScraperStrategy[contracts.RealEstate])
+
+	for _, source := range cfg.Sources {
+		list := make([]scrapify.ScraperStrategy[contracts.RealEstate], 0, len(source.Seeds))
+
+		for _, seed := range source.Seeds {
+			options := source.ScraperOptions(seed)
+			options.Collector = append(options.Collector, a.CollectorOptions(source)...)
+			options.Collector = append(options.Collector, a.metrics.Collector(source.Name))
+			options.Tracer = trace.Tee(a.metrics.Tracer(source.Name), a.Steps(source.Name))
+
+			s, err := a.Registry.Build(a.Logger, source, options)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, scrapify.ScraperStrategy[contracts.RealEstate]{
+				Scraper: s,
+				Url:     seed.Url,
+			})
+		}
+
+		strategies[source.Name] = list
+	}
+
+	return strategies, nil
+}
+
+// Connect creates the Neo4j driver from the credentials in the environment.
+// Callers must close the returned client.
+func (a *App) Connect(ctx context.Context) (*database.Neo4jClient, neo4j.DriverWithContext, error) {
+	uri := os.Getenv("NEO4J_URI")
+	username := os.Getenv("NEO4J_USERNAME")
+	password := os.Getenv("NEO4J_PASSWORD")
+
+	a.Logger.Info("Baia Scraper", "uri", uri, "username", username)
+
+	if uri == "" || username == "" || password == "" {
+		return nil, nil, errors.New("NEO4J_URI, NEO4J_USERNAME and NEO4J_PASSWORD must be set")
+	}
+
+	client := database.NewNeo4jClient(uri, username, password)
+
+	driver, err := client.GetDriver()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := driver.VerifyConnectivity(ctx); err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("neo4j connection failed: %w", err)
+	}
+
+	return client, driver, nil
+}
+
+// CheckMigrations warns when the database is missing migrations.
+func (a *App) CheckMigrations(ctx context.Context, driver neo4j.DriverWithContext) error {
+	migrator, err := database.NewMigrator(driver, a.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if pending, err := migrator.Pending(ctx); err != nil {
+		a.Logger.Warn("Failed to check database migrations", "error", err)
+	} else if len(pending) > 0 {
+		a.Logger.Warn("Database has pending migrations, run `baia migrate`", "pending", len(pending))
+	}
+
+	return nil
+}
+
+// BatchConfig returns the batching of database writes set in the environment.
+func (a *App) BatchConfig() database.BatchConfig {
+	batchSize, _ := strconv.Atoi(os.Getenv("NEO4J_BATCH_SIZE"))
+
+	return database.BatchConfig{Size: batchSize, Observe: a.metrics.Written}
+}
@@ -0,0 +1,368 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/crawl"
+	"baia/internal/quarantine"
+	"baia/internal/repository"
+	"baia/pkg/page"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/ricardocastanho/scrapify"
+)
+
+// crawlRun is one crawl session of some sources into the graph, shared by the
+// scrape and daemon commands.
+type crawlRun struct {
+	logger     *slog.Logger
+	driver     neo4j.DriverWithContext
+	sources    []config.Source
+	strategies map[string][]scrapify.ScraperStrategy[contracts.RealEstate]
+	session    *crawl.Session
+	// checkpoint, when not nil, records the progress of the crawl and skips
+	// what an earlier attempt of the session already did.
+	checkpoint *crawl.Checkpoint
+	// reportDir, when not empty, is where the report of the run is written.
+	reportDir string
+	// quarantine keeps the invalid listings of the sources that quarantine them.
+	quarantine *quarantine.Store
+	// refetch parses every detail page again, even the ones that did not
+	// change, for when a scraper was fixed.
+	refetch bool
+	// dryRun saves into an in-memory repository instead of the graph, which
+	// is then left untouched.
+	dryRun bool
+}
+
+// run scrapes the sources, saving what they yield, marks the listings that are
+// gone as delisted and reports what it did. It fails when the crawl was
+// interrupted or when listings could not be saved.
+func (r crawlRun) run(ctx context.Context, app *App) error {
+	logger := r.logger
+	session := r.session
+	recorder := crawl.NewRecorder(session)
+
+	report := func(items []contracts.RealEstate, err error) {
+		for _, item := range items {
+			if err != nil {
+				logger.Error("Failed to save real estate", "url", item.Url, "error", err)
+			} else if r.checkpoint != nil {
+				if err := r.checkpoint.Complete(item); err != nil {
+					logger.Warn("Failed to checkpoint real estate", "url", item.Url, "error", err)
+				}
+			}
+			session.Observe(recorder.Source(item.Url), item, err)
+			recorder.Observe(item, err)
+		}
+	}
+
+	var repo contracts.RealEstateRepository
+	if r.dryRun {
+		repo = repository.NewMemoryRealEstateRepository(report)
+	} else {
+		repo = repository.NewNeo4jRealEstateRepository(r.driver, app.BatchConfig(), report)
+	}
+
+	// Pages unchanged since they were last scraped are not parsed again,
+	// unless the run refetches them all.
+	known := make(map[string]page.Validators)
+	if r.refetch {
+		logger.Info("Refetching every page")
+	} else if validators, err := repo.Validators(app.Work); err != nil {
+		logger.Warn("Failed to load page validators, fetching every page", "error", err)
+	} else {
+		known = validators
+	}
+
+	drain := crawl.NewDrain(ctx, app.Work)
+	fetchFailures := 0
+	unchanged := 0
+
+	for _, source := range r.sources {
+		if ctx.Err() != nil {
+			break
+		}
+
+		logger.Info("Scraping source", "source", source.Name, "seeds", len(source.Seeds))
+		started := time.Now()
+
+		changes := app.Changes(source.Name)
+		changes.Reset(known)
+
+		// Listings held back for being invalid are still listed, so the ones
+		// in the graph must not be delisted.
+		held := &urlSet{}
+
+		// Listings extracted while the crawl is stopping are still saved.
+		callback := func(data contracts.RealEstate) {
+			data.CrawlID = session.ID
+			data.ObservedAt = time.Now().UTC()
+			data.Page, _ = changes.Fetched(data.Url)
+			steps := app.Steps(source.Name).Take(data.Url)
+			app.metrics.Parsed(source.Name)
+
+			if _, err := data.Key(); err != nil {
+				logger.Warn("Rejecting real estate", "url", data.Url, "error", err)
+				session.Observe(source.Name, data, err)
+				recorder.Observe(data, err)
+				return
+			}
+
+			violations := append(data.Validate(contracts.DefaultRules), contracts.ParseViolations(data, steps)...)
+			app.metrics.Violated(source.Name, violations)
+
+			if violations.Invalid() && source.OnInvalid != contracts.SaveInvalid {
+				logger.Warn("Holding back invalid real estate", "url", data.Url, "policy", source.OnInvalid, "violations", violations.String())
+				recorder.Held(data, source.OnInvalid)
+				held.Add(data.Url)
+				r.quarantineListing(source, r.hints(source, recorder, data.Url), data, steps, violations)
+				return
+			}
+
+			// A listing quarantined before is released once a crawl finds it valid.
+			if source.OnInvalid == contracts.QuarantineInvalid && r.quarantine != nil {
+				if err := r.quarantine.Remove(quarantine.ID(source.Name, data.Url)); err != nil {
+					logger.Warn("Failed to release real estate from quarantine", "url", data.Url, "error", err)
+				}
+			}
+
+			if violations.Severity() >= contracts.SeverityWarning {
+				logger.Warn("Saving real estate that breaks validation rules", "url", data.Url, "violations", violations.String())
+			}
+
+			logger.Info("Saving data in database:", "data", data)
+
+			// Failures are reported per real estate by the repository.
+			repo.Save(app.Work, data)
+		}
+
+		strategies := recorder.Strategies(source.Name, r.strategies[source.Name])
+		if r.checkpoint != nil {
+			strategies = r.checkpoint.Strategies(strategies)
+		}
+
+		runner := scrapify.NewScraper(drain.Strategies(strategies), callback, source.Delay)
+		runner.Run(ctx)
+		drain.Wait()
+
+		// Pages that yielded no listing keep steps no one takes.
+		app.Steps(source.Name).Reset()
+
+		// Listings behind pages that did not change were seen all the same.
+		missed := false
+
+		touched, err := repo.Touch(app.Work, changes.TakeUnchanged(), session.ID)
+		if err != nil {
+			logger.Error("Failed to touch unchanged real estates", "source", source.Name, "error", err)
+			missed = true
+		}
+		for _, item := range touched {
+			session.Observe(source.Name, item, nil)
+		}
+		recorder.Touched(touched)
+		unchanged += len(touched)
+
+		kept, err := repo.Touch(app.Work, held.Take(), session.ID)
+		if err != nil {
+			logger.Error("Failed to touch held back real estates", "source", source.Name, "error", err)
+			missed = true
+		}
+		for _, item := range kept {
+			session.Observe(source.Name, item, nil)
+		}
+
+		// Listings behind detail pages that failed for a passing reason, or
+		// that an offline cache does not have, were not seen but may still be
+		// listed, unlike the ones whose page is gone. A listing page that
+		// failed in any way hides what it lists. Seeds share listings, a seed
+		// without hints all of the agency, so either leaves the whole source
+		// out of delisting.
+		incomplete := make(map[string]bool)
+
+		if failed := app.FetchFailures(source.Name); len(failed) > 0 {
+			counts := failed.Counts()
+			for kind, count := range counts {
+				fetchFailures += count
+				app.metrics.FetchFailed(source.Name, kind, count)
+			}
+			logger.Warn("Pages could not be fetched", "source", source.Name, "failures", counts)
+
+			for url, kind := range failed {
+				seed, listing, ok := recorder.Visit(source.Name, url)
+				switch {
+				case !ok:
+					missed = missed || kind.Missed()
+				case listing || kind.Missed():
+					incomplete[seed] = true
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			logger.Warn("Crawl interrupted, skipping delisting", "source", source.Name, "error", ctx.Err())
+			app.metrics.Crawled(source.Name, started, false)
+			continue
+		}
+
+		if missed {
+			logger.Warn("Pages could not be fetched, skipping delisting", "source", source.Name)
+			app.metrics.Crawled(source.Name, started, false)
+			continue
+		}
+
+		// Delisting relies on every listing of the source being written.
+		if err := repo.Flush(ctx); err != nil {
+			logger.Warn("Failed to flush real estates, skipping delisting", "source", source.Name, "error", err)
+			app.metrics.Crawled(source.Name, started, false)
+			continue
+		}
+
+		if len(incomplete) > 0 {
+			logger.Warn("Pages could not be fetched, skipping delisting", "source", source.Name, "seeds", len(incomplete))
+			app.metrics.Crawled(source.Name, started, false)
+			continue
+		}
+
+		// Listings of the source that failed to be saved have all been
+		// reported by now.
+		app.metrics.Crawled(source.Name, started, session.SourceFailures(source.Name) == 0)
+
+		for _, seed := range source.Seeds {
+			scope := source.Hints(seed)
+
+			if !session.Complete(scope) {
+				logger.Warn("Incomplete crawl, skipping delisting", "source", source.Name, "seed", seed.Url)
+				continue
+			}
+
+			delisted, err := repo.MarkDelisted(ctx, scope, session.ID)
+			if err != nil {
+				logger.Error("Failed to mark delisted real estates", "source", source.Name, "seed", seed.Url, "error", err)
+				continue
+			}
+
+			logger.Info("Marked delisted real estates", "source", source.Name, "seed", seed.Url, "delisted", delisted)
+			recorder.Delisted(source.Name, seed.Url, scope, delisted)
+		}
+	}
+
+	// The crawl context may be over by now, pending rows get the grace period.
+	flushCtx, cancel := app.Grace()
+	defer cancel()
+
+	if err := repo.Flush(flushCtx); err != nil {
+		logger.Error("Failed to flush pending real estates", "error", err)
+	}
+
+	summary := drain.Summary()
+	logger.Info("Crawl summary",
+		"crawlId", session.ID,
+		"pages", summary.Pages,
+		"listings", summary.Listings,
+		"saved", session.Saved()-unchanged,
+		"unchanged", unchanged,
+		"failed", session.Failures(),
+		"fetchFailed", fetchFailures,
+		"skipped", summary.Skipped,
+		"abandoned", summary.Abandoned,
+	)
+
+	var runErr error
+	if err := ctx.Err(); err != nil {
+		runErr = fmt.Errorf("crawl interrupted: %w", err)
+	} else if failures := session.Failures(); failures > 0 {
+		runErr = fmt.Errorf("%d real estates failed to be saved", failures)
+	}
+
+	r.report(flushCtx, repo, recorder, runErr)
+
+	return runErr
+}
+
+// report stores the report of the run in the graph and in the reports
+// directory. Failing to do so does not fail the run.
+func (r crawlRun) report(ctx context.Context, repo contracts.RealEstateRepository, recorder *crawl.Recorder, runErr error) {
+	logger := r.logger
+
+	changes, err := repo.CrawlChanges(ctx, r.session.ID)
+	if err != nil {
+		logger.Warn("Failed to count new and repriced real estates", "error", err)
+	}
+	recorder.Changed(changes)
+
+	report := recorder.Report(runErr)
+
+	if err := repo.SaveCrawlRun(ctx, report); err != nil {
+		logger.Error("Failed to save crawl report", "crawlId", report.CrawlID, "error", err)
+	}
+
+	if r.reportDir == "" {
+		return
+	}
+
+	path, err := crawl.WriteReport(r.reportDir, report)
+	if err != nil {
+		logger.Error("Failed to write crawl report", "crawlId", report.CrawlID, "error", err)
+		return
+	}
+
+	logger.Info("Wrote crawl report", "crawlId", report.CrawlID, "path", path, "new", report.Total.New, "priceChanges", report.Total.PriceChanges)
+}
+
+// quarantineListing keeps the invalid listing in the quarantine, with the raw
+// texts of its fields, when its source quarantines invalid listings.
+func (r crawlRun) quarantineListing(source config.Source, hints contracts.Hints, data contracts.RealEstate, steps []contracts.TraceStep, violations contracts.Violations) {
+	if source.OnInvalid != contracts.QuarantineInvalid || r.quarantine == nil {
+		return
+	}
+
+	err := r.quarantine.Put(quarantineItem(source, hints, data, steps, violations))
+	if err != nil {
+		r.logger.Error("Failed to quarantine real estate", "url", data.Url, "error", err)
+	}
+}
+
+// hints returns the hints of the seed of the source whose strategy found the
+// detail page, or the ones of the source when none did.
+func (r crawlRun) hints(source config.Source, recorder *crawl.Recorder, url string) contracts.Hints {
+	if seedUrl, _, ok := recorder.Visit(source.Name, url); ok {
+		for _, seed := range source.Seeds {
+			if seed.Url == seedUrl {
+				return source.Hints(seed)
+			}
+		}
+	}
+
+	return source.Hints(config.Seed{})
+}
+
+// urlSet collects URLs from concurrent callbacks.
+type urlSet struct {
+	mutex sync.Mutex
+	urls  []string
+}
+
+func (s *urlSet) Add(url string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.urls = append(s.urls, url)
+}
+
+// Take returns the URLs added since the last call.
+func (s *urlSet) Take() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	urls := s.urls
+	s.urls = nil
+
+	return urls
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"time"
+
+	"baia/internal/api"
+	"baia/internal/config"
+	"baia/internal/crawl"
+	"baia/internal/quarantine"
+	"baia/internal/repository"
+)
+
+// daemonCommand crawls every source on its own schedule until the context is
+// done, serving the API along with the status of the schedules and the
+// metrics of the runs.
+type daemonCommand struct {
+	addr          string
+	schedule      string
+	runTimeout    time.Duration
+	reportDir     string
+	quarantineDir string
+}
+
+func (c *daemonCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", ":8080", "address to serve the API, /schedule and /metrics on, empty to serve nothing")
+	fs.StringVar(&c.schedule, "schedule", "", "cron expression of the sources that do not set a schedule")
+	fs.DurationVar(&c.runTimeout, "run-timeout", time.Minute*45, "time limit of each run, 0 for no limit")
+	fs.StringVar(&c.reportDir, "report-dir", "", "crawl reports directory, defaults to $BAIA_REPORTS or "+DefaultReportDir)
+	fs.StringVar(&c.quarantineDir, "quarantine-dir", "", "quarantine directory, defaults to $BAIA_QUARANTINE or "+DefaultQuarantineDir)
+}
+
+func (c *daemonCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+
+	cfg, err := app.Sources()
+	if err != nil {
+		return err
+	}
+
+	strategies, err := app.Strategies(cfg)
+	if err != nil {
+		return err
+	}
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := app.CheckMigrations(ctx, driver); err != nil {
+		return err
+	}
+
+	store, err := quarantine.Open(quarantineDir(c.quarantineDir))
+	if err != nil {
+		return err
+	}
+
+	scheduler := crawl.NewScheduler(app.Logger)
+
+	for _, source := range cfg.Sources {
+		schedule := source.Schedule
+		if schedule == "" {
+			schedule = c.schedule
+		}
+		if schedule == "" {
+			app.Logger.Warn("Source has no schedule, it will not run", "source", source.Name)
+			continue
+		}
+
+		// Every run is a crawl session of its own, so sources delist independently.
+		job := func(ctx context.Context) (string, error) {
+			if c.runTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.runTimeout)
+				defer cancel()
+			}
+
+			session := crawl.NewSession()
+			logger := app.Logger.With("source", source.Name)
+			logger.Info("Starting crawl session", "crawlId", session.ID)
+
+			run := crawlRun{
+				logger:     logger,
+				driver:     driver,
+				sources:    []config.Source{source},
+				strategies: strategies,
+				session:    session,
+				reportDir:  reportDir(c.reportDir),
+				quarantine: store,
+			}
+
+			return session.ID, run.run(ctx, app)
+		}
+
+		if err := scheduler.Add(source.Name, schedule, source.Jitter, job); err != nil {
+			return usagef("invalid schedule %q of source %s: %v", schedule, source.Name, err)
+		}
+	}
+
+	if len(scheduler.Status()) == 0 {
+		return usagef("no source has a schedule, set one in the sources file or with --schedule")
+	}
+
+	for _, status := range scheduler.Status() {
+		app.Logger.Info("Scheduled source", "source", status.Name, "schedule", status.Schedule)
+	}
+
+	var server *http.Server
+	errs := make(chan error, 1)
+
+	if c.addr != "" {
+		handler := api.NewServer(repository.NewNeo4jRealEstateRepository(driver, app.BatchConfig(), nil), app.Logger)
+		handler.Handle("GET /schedule", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(scheduler.Status())
+		}))
+		handler.Handle("GET /metrics", app.metrics.Handler())
+
+		server = &http.Server{
+			Addr:              c.addr,
+			Handler:           handler,
+			ReadHeaderTimeout: time.Second * 10,
+		}
+
+		go func() {
+			errs <- server.ListenAndServe()
+		}()
+
+		app.Logger.Info("Serving real estates, schedules and metrics", "addr", c.addr)
+	}
+
+	runCtx, stopRuns := context.WithCancel(ctx)
+	defer stopRuns()
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(runCtx)
+		close(done)
+	}()
+
+	select {
+	case err = <-errs:
+		// The server failed, the runs in flight get to stop like on a signal.
+		stopRuns()
+	case <-ctx.Done():
+	}
+
+	<-done
+
+	if server != nil && err == nil {
+		shutdownCtx, cancel := app.Grace()
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"text/tabwriter"
+
+	"baia/internal/contracts"
+	"baia/internal/export"
+	"baia/internal/repository"
+	"baia/internal/utils"
+	"baia/pkg/database"
+)
+
+// migrateCommand applies the pending database migrations.
+type migrateCommand struct {
+	pending bool
+}
+
+func (c *migrateCommand) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.pending, "pending", false, "only list the pending migrations")
+}
+
+func (c *migrateCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	migrator, err := database.NewMigrator(driver, app.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	if c.pending {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			fmt.Fprintf(app.Stdout, "%04d_%s\n", migration.Version, migration.Name)
+		}
+
+		return nil
+	}
+
+	applied, err := migrator.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("migration failed after applying %d migrations: %w", len(applied), err)
+	}
+
+	app.Logger.Info("Migrations completed.", "applied", len(applied))
+
+	return nil
+}
+
+// importCommand replays dumps written by export, or CSV files with the same
+// columns, through the repository used by scrape. Listings are saved from the
+// oldest to the newest observation, so price histories are rebuilt in order
+// and dated as they were observed.
+type importCommand struct{}
+
+func (c *importCommand) flags(fs *flag.FlagSet) {}
+
+func (c *importCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) == 0 {
+		return usagef("expected at least one dump")
+	}
+
+	var listings []contracts.RealEstate
+
+	for _, path := range args {
+		list, err := export.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("invalid dump %s: %w", path, err)
+		}
+
+		app.Logger.Info("Read dump", "path", path, "listings", len(list))
+		listings = append(listings, list...)
+	}
+
+	sort.SliceStable(listings, func(i, j int) bool {
+		return listings[i].ObservedAt.Before(listings[j].ObservedAt)
+	})
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := app.CheckMigrations(ctx, driver); err != nil {
+		return err
+	}
+
+	imported, failed := 0, 0
+
+	repo := repository.NewNeo4jRealEstateRepository(driver, app.BatchConfig(),
+		func(items []contracts.RealEstate, err error) {
+			if err != nil {
+				app.Logger.Error("Failed to import real estates", "count", len(items), "error", err)
+				failed += len(items)
+				return
+			}
+			imported += len(items)
+		},
+	)
+
+	for _, listing := range listings {
+		if _, err := listing.Key(); err != nil {
+			app.Logger.Warn("Rejecting real estate", "url", listing.Url, "error", err)
+			failed++
+			continue
+		}
+
+		// Failures are reported per batch by the repository.
+		repo.Save(ctx, listing)
+	}
+
+	repo.Flush(ctx)
+
+	app.Logger.Info("Import completed.", "imported", imported, "failed", failed)
+
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d of %d real estates", failed, len(listings))
+	}
+
+	return nil
+}
+
+// statsCommand prints how many real estates each agency has in the graph.
+type statsCommand struct {
+	json bool
+}
+
+func (c *statsCommand) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.json, "json", false, "print the stats as JSON")
+}
+
+func (c *statsCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+
+	var agencies []string
+	if len(app.options.sources) > 0 {
+		cfg, err := app.Sources()
+		if err != nil {
+			return err
+		}
+
+		for _, source := range cfg.Sources {
+			agencies = append(agencies, utils.NormalizeCityName(source.Agency))
+		}
+	}
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	repo := repository.NewNeo4jRealEstateRepository(driver, app.BatchConfig(), nil)
+
+	stats, err := repo.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	if agencies != nil {
+		stats = slices.DeleteFunc(stats, func(s contracts.AgencyStats) bool {
+			return !slices.Contains(agencies, utils.NormalizeCityName(s.Agency))
+		})
+	}
+
+	if c.json {
+		return json.NewEncoder(app.Stdout).Encode(stats)
+	}
+
+	w := tabwriter.NewWriter(app.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENCY\tLISTINGS\tFOR SALE\tFOR RENT\tDELISTED")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", s.Agency, s.Listings, s.ForSale, s.ForRent, s.Delisted)
+	}
+
+	return w.Flush()
+}
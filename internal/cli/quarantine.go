@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/crawl"
+	"baia/internal/quarantine"
+	"baia/internal/repository"
+	"baia/internal/scraper/trace"
+)
+
+// DefaultQuarantineDir holds the listings held back as invalid by the sources
+// that quarantine them, one JSON file per listing.
+const DefaultQuarantineDir = ".baia/quarantine"
+
+// quarantineDir returns the quarantine directory set by the flag, or else by
+// the environment, or else the default one.
+func quarantineDir(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if dir := os.Getenv("BAIA_QUARANTINE"); dir != "" {
+		return dir
+	}
+	return DefaultQuarantineDir
+}
+
+// quarantineCommand works on the quarantined listings: list prints them,
+// inspect prints one with the raw texts of its fields and its violations, and
+// reprocess scrapes them again, saving the ones that became valid, usually
+// after a fix of their scraper.
+type quarantineCommand struct {
+	dir  string
+	json bool
+}
+
+func (c *quarantineCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.dir, "quarantine-dir", "", "quarantine directory, defaults to $BAIA_QUARANTINE or "+DefaultQuarantineDir)
+	fs.BoolVar(&c.json, "json", false, "print the list as JSON")
+}
+
+func (c *quarantineCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) == 0 {
+		return usagef("expected list, inspect or reprocess")
+	}
+
+	store, err := quarantine.Open(quarantineDir(c.dir))
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) > 1 {
+			return usagef("unexpected arguments %v", args[1:])
+		}
+		return c.list(app, store)
+	case "inspect":
+		if len(args) != 2 {
+			return usagef("expected one id")
+		}
+		return c.inspect(app, store, args[1])
+	case "reprocess":
+		return c.reprocess(ctx, app, store, args[1:])
+	default:
+		return usagef("unknown quarantine action %q, expected list, inspect or reprocess", args[0])
+	}
+}
+
+// items returns the quarantined listings with the ids, or all of them, keeping
+// only the ones of the sources named by --source.
+func (c *quarantineCommand) items(app *App, store *quarantine.Store, ids []string) ([]quarantine.Item, error) {
+	var items []quarantine.Item
+
+	if len(ids) == 0 {
+		all, err := store.List()
+		if err != nil {
+			return nil, err
+		}
+		items = all
+	}
+
+	for _, id := range ids {
+		item, err := store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if len(app.options.sources) > 0 {
+		items = slices.DeleteFunc(items, func(item quarantine.Item) bool {
+			return !slices.Contains(app.options.sources, item.Source)
+		})
+	}
+
+	return items, nil
+}
+
+func (c *quarantineCommand) list(app *App, store *quarantine.Store) error {
+	items, err := c.items(app, store, nil)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return json.NewEncoder(app.Stdout).Encode(items)
+	}
+
+	w := tabwriter.NewWriter(app.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSOURCE\tQUARANTINED AT\tVIOLATIONS\tURL")
+	for _, item := range items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", item.ID, item.Source, item.QuarantinedAt.Format(time.DateTime), len(item.Violations), item.Url)
+	}
+
+	return w.Flush()
+}
+
+func (c *quarantineCommand) inspect(app *App, store *quarantine.Store, id string) error {
+	item, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(app.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(item)
+}
+
+// reprocess scrapes the quarantined listings again with the scrapers of their
+// sources. The ones found valid are saved and released from quarantine, the
+// others are quarantined again with what their page holds now.
+func (c *quarantineCommand) reprocess(ctx context.Context, app *App, store *quarantine.Store, ids []string) error {
+	items, err := c.items(app, store, ids)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		app.Logger.Info("No listing in quarantine.")
+		return nil
+	}
+
+	cfg, err := app.Sources()
+	if err != nil {
+		return err
+	}
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := app.CheckMigrations(ctx, driver); err != nil {
+		return err
+	}
+
+	logger := app.Logger
+	session := crawl.NewSession()
+	logger.Info("Reprocessing quarantined real estates", "crawlId", session.ID, "listings", len(items))
+
+	// saved maps the URLs of the listings saved to their id in the quarantine,
+	// released are the ids of the ones written.
+	saved := make(map[string]string)
+	var released []string
+	failed := 0
+
+	repo := repository.NewNeo4jRealEstateRepository(driver, app.BatchConfig(),
+		func(items []contracts.RealEstate, err error) {
+			for _, item := range items {
+				if err != nil {
+					logger.Error("Failed to save real estate", "url", item.Url, "error", err)
+					failed++
+					continue
+				}
+				released = append(released, saved[item.Url])
+			}
+		},
+	)
+
+	invalid, unreachable := 0, 0
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			break
+		}
+
+		source, ok := findSource(cfg, item.Source)
+		if !ok {
+			logger.Warn("Source of quarantined real estate is not configured", "id", item.ID, "source", item.Source)
+			unreachable++
+			continue
+		}
+
+		// Items quarantined without hints get the ones of their source.
+		hints := item.Hints
+		if hints == (contracts.Hints{}) {
+			hints = source.Hints(config.Seed{})
+		}
+
+		data, ok, err := app.scrapeListing(ctx, source, hints, item.Url)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			logger.Warn("No data extracted from quarantined real estate", "id", item.ID, "url", item.Url)
+			unreachable++
+			continue
+		}
+
+		data.CrawlID = session.ID
+		data.ObservedAt = time.Now().UTC()
+		steps := app.Steps(source.Name).Take(data.Url)
+
+		violations := append(data.Validate(contracts.DefaultRules), contracts.ParseViolations(data, steps)...)
+		if violations.Invalid() {
+			logger.Warn("Quarantined real estate is still invalid", "id", item.ID, "url", item.Url, "violations", violations.String())
+			invalid++
+
+			if err := store.Put(quarantineItem(source, hints, data, steps, violations)); err != nil {
+				logger.Error("Failed to quarantine real estate", "url", data.Url, "error", err)
+			}
+			continue
+		}
+
+		saved[data.Url] = item.ID
+
+		// Failures are reported per real estate by the repository.
+		repo.Save(app.Work, data)
+	}
+
+	flushCtx, cancel := app.Grace()
+	defer cancel()
+
+	if err := repo.Flush(flushCtx); err != nil {
+		logger.Error("Failed to flush pending real estates", "error", err)
+	}
+
+	for _, id := range released {
+		if err := store.Remove(id); err != nil {
+			logger.Warn("Failed to release real estate from quarantine", "id", id, "error", err)
+		}
+	}
+
+	logger.Info("Reprocessing completed.",
+		"crawlId", session.ID,
+		"saved", len(released),
+		"invalid", invalid,
+		"unreachable", unreachable,
+		"failed", failed,
+	)
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("reprocessing interrupted: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d real estates failed to be saved", failed)
+	}
+
+	return nil
+}
+
+// quarantineItem returns the quarantined listing of the source, found from a
+// seed with the hints, with the raw texts of its fields as traced in the steps
+// of its page.
+func quarantineItem(source config.Source, hints contracts.Hints, data contracts.RealEstate, steps []contracts.TraceStep, violations contracts.Violations) quarantine.Item {
+	return quarantine.Item{
+		Source:        source.Name,
+		Url:           data.Url,
+		CrawlID:       data.CrawlID,
+		QuarantinedAt: data.ObservedAt,
+		Hints:         hints,
+		Raw:           trace.RawTexts(steps),
+		Violations:    violations,
+		RealEstate:    data,
+	}
+}
+
+// scrapeListing scrapes the detail page with the scraper of the source and the
+// hints of the seed it was found from, tracing its fields to the steps of the
+// source. It reports whether the page yielded a listing.
+func (a *App) scrapeListing(ctx context.Context, source config.Source, hints contracts.Hints, url string) (contracts.RealEstate, bool, error) {
+	options := source.ScraperOptions(config.Seed{Url: url})
+	options.Hints = hints
+	options.Tracer = a.Steps(source.Name)
+	options.Collector = append(options.Collector, a.CollectorOptions(source)...)
+
+	s, err := a.Registry.Build(a.Logger, source, options)
+	if err != nil {
+		return contracts.RealEstate{}, false, err
+	}
+
+	ch := make(chan contracts.RealEstate, 1)
+	data := contracts.RealEstate{}
+
+	s.GetData(ctx, ch, &data, url)
+
+	select {
+	case data = <-ch:
+	default:
+		a.Steps(source.Name).Take(url)
+		return contracts.RealEstate{}, false, nil
+	}
+
+	data.Page, _ = a.Changes(source.Name).Fetched(data.Url)
+
+	return data, true, nil
+}
+
+// findSource returns the source of the sources file with the name.
+func findSource(cfg *config.Config, name string) (config.Source, bool) {
+	for _, source := range cfg.Sources {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return config.Source{}, false
+}
@@ -0,0 +1,418 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/crawl"
+	"baia/internal/export"
+	"baia/internal/quarantine"
+	"baia/internal/scraper/trace"
+
+	"github.com/ricardocastanho/scrapify"
+)
+
+// DefaultCheckpointDir holds the checkpoints of the crawls that did not complete.
+const DefaultCheckpointDir = ".baia/checkpoints"
+
+// DefaultReportDir holds the reports of the crawls, one JSON file per crawl.
+const DefaultReportDir = ".baia/reports"
+
+// reportDir returns the reports directory set by the flag, or else by the
+// environment, or else the default one.
+func reportDir(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if dir := os.Getenv("BAIA_REPORTS"); dir != "" {
+		return dir
+	}
+	return DefaultReportDir
+}
+
+// scrapeCommand crawls the sources into the graph and marks the listings that
+// are gone as delisted.
+type scrapeCommand struct {
+	resume        bool
+	runID         string
+	checkpointDir string
+	reportDir     string
+	quarantineDir string
+	refetch       bool
+	dryRun        bool
+}
+
+func (c *scrapeCommand) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.resume, "resume", false, "resume the latest crawl that did not complete")
+	fs.StringVar(&c.runID, "run-id", "", "resume the crawl with this id instead of the latest one")
+	fs.StringVar(&c.checkpointDir, "checkpoint-dir", "", "checkpoints directory, defaults to $BAIA_CHECKPOINTS or "+DefaultCheckpointDir)
+	fs.StringVar(&c.reportDir, "report-dir", "", "crawl reports directory, defaults to $BAIA_REPORTS or "+DefaultReportDir)
+	fs.StringVar(&c.quarantineDir, "quarantine-dir", "", "quarantine directory, defaults to $BAIA_QUARANTINE or "+DefaultQuarantineDir)
+	fs.BoolVar(&c.refetch, "refetch", false, "fetch and parse every detail page, even the ones that did not change, after a scraper fix")
+	fs.BoolVar(&c.dryRun, "dry-run", false, "crawl into memory, without the graph, checkpoints, quarantine or reports")
+}
+
+// session starts a new crawl session, or resumes the one asked for.
+func (c *scrapeCommand) session() (*crawl.Session, error) {
+	if c.runID != "" {
+		return crawl.ResumeSession(c.runID), nil
+	}
+
+	if !c.resume {
+		return crawl.NewSession(), nil
+	}
+
+	runID, err := crawl.LatestCheckpoint(c.checkpointDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume crawl: %w", err)
+	}
+
+	return crawl.ResumeSession(runID), nil
+}
+
+func (c *scrapeCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+
+	cfg, err := app.Sources()
+	if err != nil {
+		return err
+	}
+
+	strategies, err := app.Strategies(cfg)
+	if err != nil {
+		return err
+	}
+
+	if c.dryRun {
+		return c.dry(ctx, app, cfg, strategies)
+	}
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := app.CheckMigrations(ctx, driver); err != nil {
+		return err
+	}
+
+	store, err := quarantine.Open(quarantineDir(c.quarantineDir))
+	if err != nil {
+		return err
+	}
+
+	if c.checkpointDir == "" {
+		c.checkpointDir = os.Getenv("BAIA_CHECKPOINTS")
+	}
+	if c.checkpointDir == "" {
+		c.checkpointDir = DefaultCheckpointDir
+	}
+
+	session, err := c.session()
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := crawl.OpenCheckpoint(c.checkpointDir, session.ID)
+	if err != nil {
+		return err
+	}
+	defer checkpoint.Close()
+
+	// Listings saved before the resume count for delisting as if saved now.
+	session.Restore(checkpoint.Scopes())
+
+	pages, listings := checkpoint.Summary()
+	app.Logger.Info("Starting crawl session", "crawlId", session.ID, "resumedPages", pages, "resumedListings", listings)
+
+	run := crawlRun{
+		logger:     app.Logger,
+		driver:     driver,
+		sources:    cfg.Sources,
+		strategies: strategies,
+		session:    session,
+		checkpoint: checkpoint,
+		reportDir:  reportDir(c.reportDir),
+		quarantine: store,
+		refetch:    c.refetch,
+	}
+
+	// The checkpoint is kept for a resume to retry what is missing.
+	if err := run.run(ctx, app); err != nil {
+		return fmt.Errorf("%w, resume with --run-id %s", err, session.ID)
+	}
+
+	if err := checkpoint.Remove(); err != nil {
+		app.Logger.Warn("Failed to remove checkpoint", "crawlId", session.ID, "error", err)
+	}
+
+	app.Logger.Info("Scraping completed.")
+
+	return nil
+}
+
+// dry crawls the sources into an in-memory repository, which validates the
+// sources and their scrapers end to end without touching the graph. Nothing is
+// checkpointed, quarantined nor reported to disk.
+func (c *scrapeCommand) dry(ctx context.Context, app *App, cfg *config.Config, strategies map[string][]scrapify.ScraperStrategy[contracts.RealEstate]) error {
+	if c.resume || c.runID != "" {
+		return usagef("--dry-run cannot resume a crawl")
+	}
+
+	session := crawl.NewSession()
+	app.Logger.Info("Starting dry run", "crawlId", session.ID)
+
+	run := crawlRun{
+		logger:     app.Logger,
+		sources:    cfg.Sources,
+		strategies: strategies,
+		session:    session,
+		refetch:    c.refetch,
+		dryRun:     true,
+	}
+
+	if err := run.run(ctx, app); err != nil {
+		return err
+	}
+
+	app.Logger.Info("Dry run completed.")
+
+	return nil
+}
+
+// scrapeUrlCommand scrapes a single detail page with the scraper of a source
+// and prints what it extracted.
+type scrapeUrlCommand struct{}
+
+func (c *scrapeUrlCommand) flags(fs *flag.FlagSet) {}
+
+func (c *scrapeUrlCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) != 1 {
+		return usagef("expected one url")
+	}
+
+	source, err := app.singleSource()
+	if err != nil {
+		return err
+	}
+
+	s, err := app.Registry.Scraper(app.Logger, source, config.Seed{Url: args[0]}, app.CollectorOptions(source)...)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan contracts.RealEstate, 1)
+	data := contracts.RealEstate{}
+
+	s.GetData(ctx, ch, &data, args[0])
+
+	select {
+	case data = <-ch:
+	default:
+		return fmt.Errorf("no data extracted from %s", args[0])
+	}
+
+	encoder := json.NewEncoder(app.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(data)
+}
+
+// debugCommand scrapes a single detail page like scrape-url, reporting for
+// every field whether its selector matched, the raw text, the parsed value and
+// the error of the setter.
+type debugCommand struct {
+	json bool
+}
+
+func (c *debugCommand) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.json, "json", false, "print the report as JSON")
+}
+
+func (c *debugCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) != 1 {
+		return usagef("expected one url")
+	}
+
+	source, err := app.singleSource()
+	if err != nil {
+		return err
+	}
+
+	report := trace.NewReport()
+
+	options := source.ScraperOptions(config.Seed{Url: args[0]})
+	options.Tracer = report
+	options.Collector = append(options.Collector, app.CollectorOptions(source)...)
+
+	s, err := app.Registry.Build(app.Logger, source, options)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan contracts.RealEstate, 1)
+	data := contracts.RealEstate{}
+
+	s.GetData(ctx, ch, &data, args[0])
+
+	extracted := true
+	select {
+	case data = <-ch:
+	default:
+		extracted = false
+	}
+
+	if c.json {
+		encoder := json.NewEncoder(app.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(map[string]any{
+			"url":        args[0],
+			"fields":     report,
+			"realEstate": data,
+		})
+	} else {
+		err = report.Write(app.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !extracted {
+		return fmt.Errorf("no data extracted from %s", args[0])
+	}
+
+	return nil
+}
+
+// singleSource returns the only source left after --source, so commands that
+// work on one page know which scraper to use.
+func (a *App) singleSource() (config.Source, error) {
+	cfg, err := a.Sources()
+	if err != nil {
+		return config.Source{}, err
+	}
+
+	if len(cfg.Sources) != 1 {
+		names := make([]string, 0, len(cfg.Sources))
+		for _, source := range cfg.Sources {
+			names = append(names, source.Name)
+		}
+		return config.Source{}, usagef("choose the source of the page with --source (one of %v)", names)
+	}
+
+	return cfg.Sources[0], nil
+}
+
+// exportCommand scrapes the sources like scrape, but writes the listings as
+// JSON Lines to a file, or to the standard output when it is "-", instead of
+// saving them. Neo4j is not needed.
+type exportCommand struct{}
+
+func (c *exportCommand) flags(fs *flag.FlagSet) {}
+
+func (c *exportCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) > 1 {
+		return usagef("expected at most one output file")
+	}
+
+	output := "-"
+	if len(args) == 1 {
+		output = args[0]
+	}
+
+	cfg, err := app.Sources()
+	if err != nil {
+		return err
+	}
+
+	strategies, err := app.Strategies(cfg)
+	if err != nil {
+		return err
+	}
+
+	out := app.Stdout
+	if output != "-" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer file.Close()
+
+		out = file
+	}
+
+	logger := app.Logger
+
+	session := crawl.NewSession()
+	logger.Info("Starting export", "crawlId", session.ID, "output", output)
+
+	writer := export.NewJSONLinesWriter(out)
+
+	var exported, failed atomic.Int64
+
+	callback := func(data contracts.RealEstate) {
+		data.CrawlID = session.ID
+		data.ObservedAt = time.Now().UTC()
+
+		if err := writer.Write(data); err != nil {
+			logger.Error("Failed to export real estate", "url", data.Url, "error", err)
+			failed.Add(1)
+			return
+		}
+
+		exported.Add(1)
+	}
+
+	drain := crawl.NewDrain(ctx, app.Work)
+
+	for _, source := range cfg.Sources {
+		logger.Info("Scraping source", "source", source.Name, "seeds", len(source.Seeds))
+
+		runner := scrapify.NewScraper(drain.Strategies(strategies[source.Name]), callback, source.Delay)
+		runner.Run(ctx)
+		drain.Wait()
+
+		// Exported listings are not validated, so their steps are not needed.
+		app.Steps(source.Name).Reset()
+
+		if failed := app.FetchFailures(source.Name); len(failed) > 0 {
+			logger.Warn("Pages could not be fetched", "source", source.Name, "failures", failed.Counts())
+		}
+
+		if ctx.Err() != nil {
+			logger.Warn("Export interrupted", "source", source.Name, "error", ctx.Err())
+			break
+		}
+	}
+
+	summary := drain.Summary()
+	logger.Info("Export completed.",
+		"exported", exported.Load(),
+		"failed", failed.Load(),
+		"skipped", summary.Skipped,
+		"abandoned", summary.Abandoned,
+	)
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("export interrupted: %w", err)
+	}
+
+	if n := failed.Load(); n > 0 {
+		return fmt.Errorf("%d real estates failed to be exported", n)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"time"
+
+	"baia/internal/api"
+	"baia/internal/repository"
+)
+
+// serveCommand serves the real estates of the graph as JSON over HTTP until
+// the context is done.
+type serveCommand struct {
+	addr string
+}
+
+func (c *serveCommand) flags(fs *flag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", ":8080", "address to listen on")
+}
+
+func (c *serveCommand) run(ctx context.Context, app *App, args []string) error {
+	if len(args) > 0 {
+		return usagef("unexpected arguments %v", args)
+	}
+
+	client, driver, err := app.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := app.CheckMigrations(ctx, driver); err != nil {
+		return err
+	}
+
+	repo := repository.NewNeo4jRealEstateRepository(driver, app.BatchConfig(), nil)
+
+	server := &http.Server{
+		Addr:              c.addr,
+		Handler:           api.NewServer(repo, app.Logger),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	app.Logger.Info("Serving real estates", "addr", c.addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Requests in flight get the grace period to complete.
+	shutdownCtx, cancel := app.Grace()
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
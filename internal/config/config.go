@@ -0,0 +1,423 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"baia/internal/contracts"
+	"baia/pkg/collector"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultDelay is the politeness delay used when a source does not set one.
+const DefaultDelay = time.Second * 2
+
+// DefaultLimits are the request limits used when the sources file sets none.
+var DefaultLimits = []Limit{{Domain: "*", Parallelism: 2}}
+
+// DefaultRetry is how failed fetches are retried when the sources file does
+// not set it.
+var DefaultRetry = Retry{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: time.Second * 30}
+
+// Config is the root of the sources file.
+type Config struct {
+	Crawler Crawler  `yaml:"crawler"`
+	Sources []Source `yaml:"sources"`
+}
+
+// Crawler holds how politely every source is crawled.
+type Crawler struct {
+	// UserAgent identifies the crawler to the sites, with a URL to contact its operators.
+	UserAgent string  `yaml:"userAgent"`
+	Limits    []Limit `yaml:"limits"`
+	Retry     Retry   `yaml:"retry"`
+}
+
+// Retry tells how the fetches that failed with a timeout, a network error, a
+// 429 or a 5xx are retried, see collector.RetryPolicy.
+type Retry struct {
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff"`
+}
+
+// Limit caps the requests to the domains matching a glob, see colly.LimitRule.
+// The first limit matching a domain applies.
+type Limit struct {
+	Domain      string        `yaml:"domain"`
+	Parallelism int           `yaml:"parallelism"`
+	Delay       time.Duration `yaml:"delay"`
+	RandomDelay time.Duration `yaml:"randomDelay"`
+}
+
+// Source describes one agency website and how it should be scraped.
+type Source struct {
+	Name         string        `yaml:"name"`
+	Agency       string        `yaml:"agency"`
+	Scraper      string        `yaml:"scraper"`
+	Delay        time.Duration `yaml:"delay"`
+	Transaction  string        `yaml:"transaction"`
+	PropertyType string        `yaml:"propertyType"`
+	Seeds        []Seed        `yaml:"seeds"`
+	Selectors    *Selectors    `yaml:"selectors"`
+	// StructuredDataFallback completes the fields the scraper left empty with
+	// the schema.org JSON-LD or microdata of the detail page.
+	StructuredDataFallback bool `yaml:"structuredDataFallback"`
+	// Schedule is the cron expression of the source runs in daemon mode, and
+	// Jitter the most a run may be delayed past it.
+	Schedule string        `yaml:"schedule"`
+	Jitter   time.Duration `yaml:"jitter"`
+	// IgnoreRobotsTxt crawls the source regardless of its robots.txt, for
+	// agencies that allowed it explicitly.
+	IgnoreRobotsTxt bool `yaml:"ignoreRobotsTxt"`
+	// OnInvalid is what is done with the listings that break a validation rule
+	// of error severity: save (the default), quarantine or drop.
+	OnInvalid contracts.InvalidPolicy `yaml:"onInvalid"`
+}
+
+// Seed is a listing page where a crawl of a source starts. Transaction and
+// property type set here take precedence over the ones set on the source.
+type Seed struct {
+	Url          string `yaml:"url"`
+	Transaction  string `yaml:"transaction"`
+	PropertyType string `yaml:"propertyType"`
+}
+
+// SelectorFields lists the real estate fields a selectors block can extract.
+var SelectorFields = []string{
+	"code",
+	"name",
+	"description",
+	"price",
+	"bedrooms",
+	"bathrooms",
+	"area",
+	"garageSpaces",
+	"district",
+	"city",
+	"furnished",
+	"yearBuilt",
+	"photos",
+	"tags",
+}
+
+// Selectors describes where a listing page keeps its links and where a detail
+// page keeps each real estate field, for scrapers driven by configuration.
+type Selectors struct {
+	ListingLink string           `yaml:"listingLink"`
+	Pagination  string           `yaml:"pagination"`
+	Fields      map[string]Field `yaml:"fields"`
+}
+
+// Field tells how to extract one real estate field from a detail page.
+// The value is the text of the matched element, or the attribute named in Attr.
+// Elements matching Remove are dropped before the text is read, and when Regex
+// is set the value becomes its first capture group (or the whole match).
+// Truthy lists the values considered true for boolean fields; without it any
+// match counts as true.
+type Field struct {
+	Selector string   `yaml:"selector"`
+	Attr     string   `yaml:"attr"`
+	Remove   string   `yaml:"remove"`
+	Regex    string   `yaml:"regex"`
+	Truthy   []string `yaml:"truthy"`
+}
+
+// Load reads the sources file at the given path, applies defaults and validates it.
+func Load(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sources file: %w", err)
+	}
+
+	return Parse(content)
+}
+
+// Parse decodes a sources file, applies defaults and validates it.
+func Parse(content []byte) (*Config, error) {
+	var cfg Config
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode sources file: %w", err)
+	}
+
+	if cfg.Crawler.UserAgent == "" {
+		cfg.Crawler.UserAgent = collector.DefaultUserAgent
+	}
+	if len(cfg.Crawler.Limits) == 0 {
+		cfg.Crawler.Limits = slices.Clone(DefaultLimits)
+	}
+	if cfg.Crawler.Retry.MaxAttempts == 0 {
+		cfg.Crawler.Retry.MaxAttempts = DefaultRetry.MaxAttempts
+	}
+	if cfg.Crawler.Retry.Backoff == 0 {
+		cfg.Crawler.Retry.Backoff = DefaultRetry.Backoff
+	}
+	if cfg.Crawler.Retry.MaxBackoff == 0 {
+		cfg.Crawler.Retry.MaxBackoff = max(DefaultRetry.MaxBackoff, cfg.Crawler.Retry.Backoff)
+	}
+
+	for i := range cfg.Sources {
+		if cfg.Sources[i].Delay == 0 {
+			cfg.Sources[i].Delay = DefaultDelay
+		}
+		if cfg.Sources[i].OnInvalid == "" {
+			cfg.Sources[i].OnInvalid = contracts.SaveInvalid
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks every source and returns all the problems found at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if len(c.Sources) == 0 {
+		errs = append(errs, errors.New("no sources configured"))
+	}
+
+	if err := c.Crawler.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	names := make(map[string]bool)
+
+	for i, s := range c.Sources {
+		if s.Name == "" {
+			errs = append(errs, fmt.Errorf("source #%d: name is required", i+1))
+		} else if names[s.Name] {
+			errs = append(errs, fmt.Errorf("source %q: duplicated name", s.Name))
+		}
+		names[s.Name] = true
+
+		if err := s.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the user agent, the limits and the retries.
+func (c *Crawler) Validate() error {
+	var errs []error
+
+	if !strings.Contains(c.UserAgent, "http://") && !strings.Contains(c.UserAgent, "https://") {
+		errs = append(errs, fmt.Errorf("crawler: userAgent %q must include a contact URL", c.UserAgent))
+	}
+
+	for _, limit := range c.Limits {
+		rule := limit.Rule()
+		if err := rule.Init(); err != nil {
+			errs = append(errs, fmt.Errorf("crawler: invalid limit domain %q: %w", limit.Domain, err))
+		}
+		if limit.Parallelism < 0 || limit.Delay < 0 || limit.RandomDelay < 0 {
+			errs = append(errs, fmt.Errorf("crawler: limit of %q must not be negative", limit.Domain))
+		}
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		errs = append(errs, errors.New("crawler: retry maxAttempts must be at least 1"))
+	}
+	if c.Retry.Backoff < 0 || c.Retry.MaxBackoff < c.Retry.Backoff {
+		errs = append(errs, errors.New("crawler: retry backoff must not be negative nor above maxBackoff"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// RetryPolicy returns the retry settings as a collector policy.
+func (c *Crawler) RetryPolicy() collector.RetryPolicy {
+	return collector.RetryPolicy{
+		MaxAttempts: c.Retry.MaxAttempts,
+		Backoff:     c.Retry.Backoff,
+		MaxBackoff:  c.Retry.MaxBackoff,
+	}
+}
+
+// LimitRules returns the limits as colly rules.
+func (c *Crawler) LimitRules() []*colly.LimitRule {
+	rules := make([]*colly.LimitRule, 0, len(c.Limits))
+	for _, limit := range c.Limits {
+		rules = append(rules, limit.Rule())
+	}
+
+	return rules
+}
+
+// Rule returns the limit as a colly rule.
+func (l Limit) Rule() *colly.LimitRule {
+	return &colly.LimitRule{
+		DomainGlob:  l.Domain,
+		Parallelism: l.Parallelism,
+		Delay:       l.Delay,
+		RandomDelay: l.RandomDelay,
+	}
+}
+
+// Select keeps only the sources with the given names, in the order of the
+// file. Without names every source is kept.
+func (c *Config) Select(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var errs []error
+
+	for _, name := range names {
+		if !slices.ContainsFunc(c.Sources, func(s Source) bool { return s.Name == name }) {
+			errs = append(errs, fmt.Errorf("unknown source %q", name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	c.Sources = slices.DeleteFunc(c.Sources, func(s Source) bool {
+		return !slices.Contains(names, s.Name)
+	})
+
+	return nil
+}
+
+// Validate checks a single source.
+func (s *Source) Validate() error {
+	var errs []error
+
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("source %q: %s", s.Name, fmt.Sprintf(format, args...)))
+	}
+
+	if s.Scraper == "" {
+		fail("scraper is required")
+	}
+	if s.Agency == "" {
+		fail("agency is required")
+	}
+	if s.Delay < 0 {
+		fail("delay must not be negative")
+	}
+	if s.Schedule != "" {
+		if _, err := cron.ParseStandard(s.Schedule); err != nil {
+			fail("invalid schedule %q: %v", s.Schedule, err)
+		}
+	}
+	if s.Jitter < 0 {
+		fail("jitter must not be negative")
+	}
+	if s.OnInvalid != "" && !s.OnInvalid.Valid() {
+		fail("invalid onInvalid %q, expected save, quarantine or drop", s.OnInvalid)
+	}
+	if !validTransaction(s.Transaction) {
+		fail("invalid transaction %q", s.Transaction)
+	}
+	if !validPropertyType(s.PropertyType) {
+		fail("invalid property type %q", s.PropertyType)
+	}
+	if len(s.Seeds) == 0 {
+		fail("at least one seed is required")
+	}
+
+	if s.Selectors != nil {
+		if err := s.Selectors.Validate(); err != nil {
+			fail("%v", err)
+		}
+	}
+
+	for _, seed := range s.Seeds {
+		u, err := url.Parse(seed.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("invalid seed url %q", seed.Url)
+		}
+		if !validTransaction(seed.Transaction) {
+			fail("seed %q: invalid transaction %q", seed.Url, seed.Transaction)
+		}
+		if !validPropertyType(seed.PropertyType) {
+			fail("seed %q: invalid property type %q", seed.Url, seed.PropertyType)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that the selectors block names known fields and compiles.
+func (s *Selectors) Validate() error {
+	var errs []error
+
+	if s.ListingLink == "" {
+		errs = append(errs, errors.New("selectors: listingLink is required"))
+	}
+
+	for name, field := range s.Fields {
+		if !slices.Contains(SelectorFields, name) {
+			errs = append(errs, fmt.Errorf("selectors: unknown field %q", name))
+		}
+		if field.Selector == "" {
+			errs = append(errs, fmt.Errorf("selectors: field %q has no selector", name))
+		}
+		if field.Regex != "" {
+			if _, err := regexp.Compile(field.Regex); err != nil {
+				errs = append(errs, fmt.Errorf("selectors: field %q has an invalid regex: %w", name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ScraperOptions returns the options of the scraper that handles the seed.
+func (s *Source) ScraperOptions(seed Seed) contracts.ScraperOptions {
+	return contracts.ScraperOptions{
+		Hints:                  s.Hints(seed),
+		StructuredDataFallback: s.StructuredDataFallback,
+	}
+}
+
+// Hints returns what the source knows about the listings reachable from the seed.
+func (s *Source) Hints(seed Seed) contracts.Hints {
+	hints := contracts.Hints{
+		Agency:      s.Agency,
+		Transaction: s.Transaction,
+		Type:        s.PropertyType,
+	}
+
+	if seed.Transaction != "" {
+		hints.Transaction = seed.Transaction
+	}
+	if seed.PropertyType != "" {
+		hints.Type = seed.PropertyType
+	}
+
+	return hints
+}
+
+func validTransaction(t string) bool {
+	return t == "" || t == contracts.Sale || t == contracts.Rent
+}
+
+func validPropertyType(t string) bool {
+	switch t {
+	case "", contracts.House, contracts.Apartment, contracts.Land, contracts.Commercial, contracts.Industrial:
+		return true
+	}
+	return false
+}
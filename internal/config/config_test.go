@@ -0,0 +1,118 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/pkg/collector"
+)
+
+// source is a valid source, to which the cases add what they break.
+const source = `
+  - name: perfil
+    agency: Perfil
+    scraper: perfil
+    seeds:
+      - url: https://www.imobiliariaperfil.imb.br/comprar-imoveis/&pg=1
+`
+
+// load writes the sources file and loads it.
+func load(t *testing.T, content string) (*config.Config, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sources.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return config.Load(path)
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"unknown field", "sources:" + source + "    color: blue\n", []string{"failed to decode sources file", "color"}},
+		{"no sources", "crawler:\n  userAgent: baia (+https://example.com)\n", []string{"no sources configured"}},
+		{"user agent without contact url", "crawler:\n  userAgent: baia/1.0\nsources:" + source, []string{`userAgent "baia/1.0" must include a contact URL`}},
+		{"negative limit", "crawler:\n  limits:\n    - domain: \"*\"\n      delay: -1s\nsources:" + source, []string{`limit of "*" must not be negative`}},
+		{"limit without domain", "crawler:\n  limits:\n    - parallelism: 2\nsources:" + source, []string{`invalid limit domain ""`}},
+		{"no attempts", "crawler:\n  retry:\n    maxAttempts: -1\nsources:" + source, []string{"maxAttempts must be at least 1"}},
+		{"backoff above max backoff", "crawler:\n  retry:\n    backoff: 1m\n    maxBackoff: 10s\nsources:" + source, []string{"backoff must not be negative nor above maxBackoff"}},
+		{"source without name", "sources:\n  - agency: Perfil\n    scraper: perfil\n    seeds:\n      - url: https://a.com.br\n", []string{"source #1: name is required"}},
+		{"duplicated name", "sources:" + source + source, []string{`source "perfil": duplicated name`}},
+		{"no scraper nor agency", "sources:\n  - name: perfil\n    seeds:\n      - url: https://a.com.br\n", []string{"scraper is required", "agency is required"}},
+		{"no seeds", "sources:\n  - name: perfil\n    agency: Perfil\n    scraper: perfil\n", []string{"at least one seed is required"}},
+		{"negative delay", "sources:" + source + "    delay: -2s\n", []string{"delay must not be negative"}},
+		{"invalid schedule", "sources:" + source + "    schedule: every night\n", []string{`invalid schedule "every night"`}},
+		{"negative jitter", "sources:" + source + "    jitter: -1m\n", []string{"jitter must not be negative"}},
+		{"invalid policy", "sources:" + source + "    onInvalid: ignore\n", []string{`invalid onInvalid "ignore"`}},
+		{"invalid transaction", "sources:" + source + "    transaction: lease\n", []string{`invalid transaction "lease"`}},
+		{"invalid property type", "sources:" + source + "    propertyType: Castle\n", []string{`invalid property type "Castle"`}},
+		{"seed url without scheme", "sources:\n  - name: perfil\n    agency: Perfil\n    scraper: perfil\n    seeds:\n      - url: www.a.com.br\n", []string{`invalid seed url "www.a.com.br"`}},
+		{"seed url not over http", "sources:\n  - name: perfil\n    agency: Perfil\n    scraper: perfil\n    seeds:\n      - url: ftp://a.com.br\n", []string{`invalid seed url "ftp://a.com.br"`}},
+		{"invalid seed transaction", "sources:" + source + "        transaction: lease\n", []string{`invalid transaction "lease"`}},
+		{"selectors without listing link", "sources:" + source + "    selectors:\n      fields:\n        price:\n          selector: .price\n", []string{"listingLink is required"}},
+		{"selectors of an unknown field", "sources:" + source + "    selectors:\n      listingLink: a.card\n      fields:\n        color:\n          selector: .color\n", []string{`unknown field "color"`}},
+		{"selector missing", "sources:" + source + "    selectors:\n      listingLink: a.card\n      fields:\n        price:\n          regex: \\d+\n", []string{`field "price" has no selector`}},
+		{"invalid regex", "sources:" + source + "    selectors:\n      listingLink: a.card\n      fields:\n        price:\n          selector: .price\n          regex: \"(\"\n", []string{`field "price" has an invalid regex`}},
+		{"every problem at once", "crawler:\n  userAgent: baia\nsources:\n  - name: perfil\n", []string{"contact URL", "scraper is required", "agency is required", "at least one seed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.content)
+			if err == nil {
+				t.Fatal("Load() succeeded, want an error")
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Load() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := config.Load(filepath.Join(t.TempDir(), "sources.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "failed to read sources file") {
+		t.Errorf("Load() error = %v, want a read error", err)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(t, "sources:"+source)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Crawler.UserAgent != collector.DefaultUserAgent {
+		t.Errorf("user agent = %q, want %q", cfg.Crawler.UserAgent, collector.DefaultUserAgent)
+	}
+	if cfg.Crawler.Retry != config.DefaultRetry {
+		t.Errorf("retry = %+v, want %+v", cfg.Crawler.Retry, config.DefaultRetry)
+	}
+	if len(cfg.Crawler.Limits) != len(config.DefaultLimits) {
+		t.Errorf("limits = %+v, want %+v", cfg.Crawler.Limits, config.DefaultLimits)
+	}
+
+	s := cfg.Sources[0]
+	if s.Delay != config.DefaultDelay || s.OnInvalid != contracts.SaveInvalid {
+		t.Errorf("source delay %s and onInvalid %q, want %s and %q", s.Delay, s.OnInvalid, config.DefaultDelay, contracts.SaveInvalid)
+	}
+}
+
+// TestLoadSourcesFile loads the sources file of the repository.
+func TestLoadSourcesFile(t *testing.T) {
+	if _, err := config.Load("../../sources.yaml"); err != nil {
+		t.Errorf("Load() error = %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package contracts
+
+import "time"
+
+// CrawlCounts counts what a crawl did.
+type CrawlCounts struct {
+	ListingPages int `json:"listingPages"`
+	DetailPages  int `json:"detailPages"`
+	Saved        int `json:"saved"`
+	Failed       int `json:"failed"`
+	// Unchanged are the listings whose page did not change since it was last
+	// scraped, which were not saved again.
+	Unchanged    int `json:"unchanged"`
+	New          int `json:"new"`
+	PriceChanges int `json:"priceChanges"`
+	Delisted     int `json:"delisted"`
+	// Quarantined and Dropped are the invalid listings held back from the
+	// graph by the policy of their source.
+	Quarantined int `json:"quarantined"`
+	Dropped     int `json:"dropped"`
+	// Coverage is, by field, the percentage of the saved listings that have it.
+	Coverage map[string]float64 `json:"coverage"`
+}
+
+// StrategyReport is what a crawl did with one seed of a source.
+type StrategyReport struct {
+	Source string `json:"source"`
+	Seed   string `json:"seed"`
+	CrawlCounts
+}
+
+// CrawlReport is what a crawl run did, in total and strategy by strategy.
+type CrawlReport struct {
+	CrawlID    string    `json:"crawlId"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Error is why the run failed, if it did.
+	Error string `json:"error,omitempty"`
+	// Agencies are the agencies whose listings the run saw or delisted.
+	Agencies   []string         `json:"agencies"`
+	Total      CrawlCounts      `json:"total"`
+	Strategies []StrategyReport `json:"strategies"`
+}
+
+// CrawlChanges are the URLs of the real estates a crawl created and of the
+// ones whose price it changed.
+type CrawlChanges struct {
+	New      []string
+	Repriced []string
+}
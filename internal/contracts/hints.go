@@ -0,0 +1,49 @@
+package contracts
+
+const (
+	Sale string = "sale"
+	Rent string = "rent"
+)
+
+// Hints holds what a source already knows about the listings behind a seed URL,
+// so scrapers do not need to guess it from the page or the URL.
+type Hints struct {
+	Agency      string `json:"agency,omitempty"`
+	Transaction string `json:"transaction,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// Apply overrides the fields of the real estate that the hints know about.
+func (h Hints) Apply(r *RealEstate) {
+	if h.Agency != "" {
+		r.Agency = h.Agency
+	}
+
+	switch h.Transaction {
+	case Sale:
+		r.ForSale = true
+		r.ForRent = false
+	case Rent:
+		r.ForSale = false
+		r.ForRent = true
+	}
+
+	if h.Type != "" {
+		r.Type = h.Type
+	}
+}
+
+// Covers reports whether the listings described by other fall inside the
+// listings described by h. Empty transaction or type in h match any value.
+func (h Hints) Covers(other Hints) bool {
+	if h.Agency != other.Agency {
+		return false
+	}
+	if h.Transaction != "" && h.Transaction != other.Transaction {
+		return false
+	}
+	if h.Type != "" && h.Type != other.Type {
+		return false
+	}
+	return true
+}
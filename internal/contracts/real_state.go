@@ -2,13 +2,12 @@ package contracts
 
 import (
 	"baia/internal/utils"
-	"context"
+	"baia/internal/utils/brparse"
+	"baia/pkg/page"
 	"errors"
-	"fmt"
-	"strconv"
+	"net/url"
 	"strings"
-
-	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"time"
 )
 
 const (
@@ -20,27 +19,38 @@ const (
 )
 
 type RealEstate struct {
-	ID             string
-	Code           string
-	Type           string
-	Name           string
-	NormalizedName string
-	Description    string
-	Url            string
-	Price          int
-	Bedrooms       int
-	Bathrooms      int
-	Area           int
-	GarageSpaces   int
-	City           string
-	District       string
-	Furnished      bool
-	YearBuilt      int
-	Photos         []string
-	Tags           []string
-	Agency         string
-	ForSale        bool
-	ForRent        bool
+	ID             string    `json:"id"`
+	Code           string    `json:"code"`
+	Type           string    `json:"type"`
+	Name           string    `json:"name"`
+	NormalizedName string    `json:"normalizedName"`
+	Description    string    `json:"description"`
+	Url            string    `json:"url"`
+	Price          int       `json:"price"`
+	Bedrooms       int       `json:"bedrooms"`
+	Bathrooms      int       `json:"bathrooms"`
+	Area           int       `json:"area"`
+	GarageSpaces   int       `json:"garageSpaces"`
+	City           string    `json:"city"`
+	District       string    `json:"district"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Furnished      bool      `json:"furnished"`
+	YearBuilt      int       `json:"yearBuilt"`
+	Photos         []string  `json:"photos"`
+	Tags           []string  `json:"tags"`
+	Agency         string    `json:"agency"`
+	ForSale        bool      `json:"forSale"`
+	ForRent        bool      `json:"forRent"`
+	Delisted       bool      `json:"delisted"`
+	CrawlID        string    `json:"crawlId"`
+	ObservedAt     time.Time `json:"observedAt"`
+	// PriceOnRequest is set for listings whose price the agency only tells
+	// when asked, which have no price.
+	PriceOnRequest bool `json:"priceOnRequest"`
+	// Page holds the validators of the detail page the real estate was
+	// scraped from, to tell on the next crawl whether it changed.
+	Page page.Validators `json:"-"`
 }
 
 func (r *RealEstate) SetCode(text string) error {
@@ -58,27 +68,23 @@ func (r *RealEstate) SetDescription(description string) error {
 	return nil
 }
 
+// SetPrice sets the price, in whole reais, from texts like "R$ 1.250.000,00",
+// "R$ 1,2 mi" or "Sob consulta". The lowest price of ranges and of "a partir
+// de" is kept.
 func (r *RealEstate) SetPrice(text string) error {
-	if !strings.Contains(text, "R$") {
-		return errors.New("invalid format: keywork 'R$' is missing")
-	}
-
-	priceText := strings.TrimSpace(strings.Split(text, "R$")[1])
-	priceText = strings.ReplaceAll(priceText, ".", "")
-	priceText = strings.Split(priceText, ",")[0]
-
-	number, err := strconv.Atoi(priceText)
+	price, err := brparse.ParsePrice(text)
 	if err != nil {
 		return errors.New("error while converting the price: " + err.Error())
 	}
 
-	r.Price = number
+	r.Price = int(price.Min)
+	r.PriceOnRequest = price.Kind == brparse.PriceOnRequest
 
 	return nil
 }
 
 func (r *RealEstate) SetBedrooms(text string) error {
-	number, err := strconv.Atoi(text)
+	number, err := brparse.Integer(text)
 	if err != nil {
 		return errors.New("error while converting the bedroom field: " + err.Error())
 	}
@@ -89,7 +95,7 @@ func (r *RealEstate) SetBedrooms(text string) error {
 }
 
 func (r *RealEstate) SetBathrooms(text string) error {
-	number, err := strconv.Atoi(text)
+	number, err := brparse.Integer(text)
 	if err != nil {
 		return errors.New("error while converting the bathroom field: " + err.Error())
 	}
@@ -99,22 +105,22 @@ func (r *RealEstate) SetBathrooms(text string) error {
 	return nil
 }
 
+// SetArea sets the area, in whole square meters, from texts like "180,50 m²",
+// "2 ha" or "Área construída: 120 m² Área total: 300 m²", keeping the built
+// area when there is one.
 func (r *RealEstate) SetArea(text string) error {
-	areaText := strings.Split(text, " m²")[0]
-	areaText = strings.ReplaceAll(areaText, ".", "")
-	areaText = strings.Split(areaText, ",")[0]
-	number, err := strconv.Atoi(areaText)
+	areas, err := brparse.ParseAreas(text)
 	if err != nil {
 		return errors.New("error while converting the area field: " + err.Error())
 	}
 
-	r.Area = number
+	r.Area = int(areas.Main().SquareMeters())
 
 	return nil
 }
 
 func (r *RealEstate) SetGarageSpaces(text string) error {
-	number, err := strconv.Atoi(text)
+	number, err := brparse.Integer(text)
 	if err != nil {
 		return errors.New("error while converting the garage spaces field: " + err.Error())
 	}
@@ -140,7 +146,7 @@ func (r *RealEstate) SetFurnished(is bool) error {
 }
 
 func (r *RealEstate) SetYearBuilt(text string) error {
-	number, err := strconv.Atoi(text)
+	number, err := brparse.Integer(text)
 	if err != nil {
 		return errors.New("error while converting the year built field: " + err.Error())
 	}
@@ -160,152 +166,103 @@ func (r *RealEstate) SetTag(tag string) error {
 	return nil
 }
 
-func (r *RealEstate) Save(ctx context.Context, driver neo4j.DriverWithContext) error {
-	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		realEstateLabels := []string{"RealEstate"}
-		priceLabels := []string{"Price"}
-
-		if r.Type != "" {
-			realEstateLabels = append(realEstateLabels, r.Type)
-		}
-		if r.ForSale {
-			realEstateLabels = append(realEstateLabels, "ForSale")
-			priceLabels = append(priceLabels, "SalePrice")
+// FillMissing copies into r the fields of other that r left empty.
+// It lets a secondary source of data complete what the main extraction missed.
+func (r *RealEstate) FillMissing(other RealEstate) {
+	fillString := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
 		}
-		if r.ForRent {
-			realEstateLabels = append(realEstateLabels, "ForRent")
-			priceLabels = append(priceLabels, "RentalPrice")
+	}
+	fillInt := func(dst *int, src int) {
+		if *dst == 0 {
+			*dst = src
 		}
+	}
 
-		realEstateLabelString := fmt.Sprintf("SET r:%s", strings.Join(realEstateLabels, ":"))
-		priceLabelString := strings.Join(priceLabels, ":")
-
-		query := fmt.Sprintf(`
-			MERGE (r:RealEstate {code: $code})	
-			ON CREATE SET
-					r.id = randomUUID(),
-					r.type = $type,
-					r.name = $name,
-					r.description = $description,
-					r.url = $url,
-					r.bedrooms = $bedrooms,
-					r.bathrooms = $bathrooms,
-					r.area = $area,
-					r.garageSpaces = $garageSpaces,
-					r.furnished = $furnished,
-					r.yearBuilt = $yearBuilt,
-					r.photos = $photos,
-					r.tags = $tags,
-					r.forSale = $forSale,
-					r.forRent = $forRent,
-					r.createdAt = datetime(),
-					r.updatedAt = datetime()
-			ON MATCH SET
-					r.type = $type,
-					r.name = $name,
-					r.description = $description,
-					r.url = $url,
-					r.bedrooms = $bedrooms,
-					r.bathrooms = $bathrooms,
-					r.area = $area,
-					r.garageSpaces = $garageSpaces,
-					r.furnished = $furnished,
-					r.yearBuilt = $yearBuilt,
-					r.photos = $photos,
-					r.tags = $tags,
-					r.forSale = $forSale,
-					r.forRent = $forRent,
-					r.updatedAt = datetime()
-			%s
-			WITH r
-			CALL {
-				WITH r
-				WITH r AS r2
-				OPTIONAL MATCH (r2)-[oldRel:LATEST_PRICE]->(oldPrice:%s)
-				WHERE oldPrice IS NULL OR oldPrice.value <> $price
-		
-				CALL {
-					WITH r2, oldRel, oldPrice
-					DELETE oldRel
-					WITH r2, oldPrice
-					CREATE (newPrice:%s {
-						id: randomUUID(),
-						value: $price,
-						createdAt: datetime()
-					})
-					CREATE (r2)-[:LATEST_PRICE]->(newPrice)
-					FOREACH (_ IN CASE WHEN oldPrice IS NOT NULL THEN [1] ELSE [] END |
-						CREATE (newPrice)<-[:NEXT]-(oldPrice)
-					)
-					WITH r2, newPrice
-					OPTIONAL MATCH (r2)-[:FIRST_PRICE]->(p:%s)
-					WITH r2, newPrice, COUNT(p) AS existingFirst
-					WHERE existingFirst = 0
-					CREATE (r2)-[:FIRST_PRICE]->(newPrice)
-				}
-			}
-			MERGE (a:Agency {normalizedName: $normalizedAgencyName})
-			ON CREATE SET
-					a.id = randomUUID(),
-					a.name = $agency,
-					a.normalizedName = $normalizedAgencyName
-			MERGE (r)-[:SELLED_BY]->(a)
-			WITH r
-			MERGE (e:Estate {name: "Rio Grande do Sul", normalizedName: "riograndedosul"})
-			WITH r, e
-			MERGE (c:City {normalizedName: $normalizedCityName})
-			ON CREATE SET
-					c.id = randomUUID(),
-					c.name = $city,
-					c.normalizedName = $normalizedCityName
-			WITH r, e, c
-			MERGE (c)-[:IN]->(e)
-			MERGE (r)-[:IN]->(c)
-			WITH r, c
-			WHERE NOT $district = "" AND $district IS NOT NULL 
-			MERGE (d:District {name: $district})
-			ON CREATE SET
-					d.id = randomUUID(),
-					d.name = $district
-			WITH r, d, c
-			MERGE (d)-[:IN]->(c)
-			WITH r, d
-			CREATE (r)-[:IN]->(d)	
-			RETURN r
-		`, realEstateLabelString, priceLabelString, priceLabelString, priceLabelString)
-
-		_, err := tx.Run(ctx, query, map[string]any{
-			"code":                 r.Code,
-			"type":                 r.Type,
-			"name":                 r.Name,
-			"description":          r.Description,
-			"url":                  r.Url,
-			"price":                r.Price,
-			"bedrooms":             r.Bedrooms,
-			"bathrooms":            r.Bathrooms,
-			"area":                 r.Area,
-			"city":                 r.City,
-			"normalizedCityName":   utils.NormalizeCityName(r.City),
-			"agency":               r.Agency,
-			"normalizedAgencyName": utils.NormalizeCityName(r.Agency),
-			"district":             r.District,
-			"garageSpaces":         r.GarageSpaces,
-			"furnished":            r.Furnished,
-			"yearBuilt":            r.YearBuilt,
-			"photos":               r.Photos,
-			"tags":                 r.Tags,
-			"forSale":              r.ForSale,
-			"forRent":              r.ForRent,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute query: %w", err)
-		}
+	fillString(&r.Code, other.Code)
+	fillString(&r.Type, other.Type)
+	fillString(&r.Name, other.Name)
+	fillString(&r.Description, other.Description)
+	fillString(&r.City, other.City)
+	fillString(&r.District, other.District)
+	if r.Price == 0 && !r.PriceOnRequest {
+		r.Price = other.Price
+		r.PriceOnRequest = other.PriceOnRequest
+	}
+	fillInt(&r.Bedrooms, other.Bedrooms)
+	fillInt(&r.Bathrooms, other.Bathrooms)
+	fillInt(&r.Area, other.Area)
+	fillInt(&r.GarageSpaces, other.GarageSpaces)
+	fillInt(&r.YearBuilt, other.YearBuilt)
+
+	if r.Latitude == 0 && r.Longitude == 0 {
+		r.Latitude = other.Latitude
+		r.Longitude = other.Longitude
+	}
+	if !r.ForSale && !r.ForRent {
+		r.ForSale = other.ForSale
+		r.ForRent = other.ForRent
+	}
+	if len(r.Photos) == 0 {
+		r.Photos = other.Photos
+	}
+	if len(r.Tags) == 0 {
+		r.Tags = other.Tags
+	}
+}
+
+// ErrNoIdentity is returned for real estates that cannot be told apart from
+// others, because they have no agency or neither a code nor a URL.
+var ErrNoIdentity = errors.New("real estate has no usable identity: agency and code or url are required")
+
+// Key returns the identity of the real estate in the graph. Listing codes are
+// only unique within an agency, so the key is the normalized agency name plus
+// the code, or plus the canonical URL when the agency shows no code.
+func (r RealEstate) Key() (string, error) {
+	agency := utils.NormalizeCityName(r.Agency)
+	code := strings.TrimSpace(r.Code)
+
+	switch {
+	case agency == "":
+		return "", ErrNoIdentity
+	case code != "":
+		return agency + ":" + code, nil
+	case canonicalUrl(r.Url) != "":
+		return agency + ":url:" + canonicalUrl(r.Url), nil
+	}
+
+	return "", ErrNoIdentity
+}
+
+// Scope returns the agency, transaction and type of the real estate, in the
+// same terms a source uses to describe the listings of a seed.
+func (r RealEstate) Scope() Hints {
+	scope := Hints{Agency: r.Agency, Type: r.Type}
 
-		return nil, nil
-	})
+	if r.ForSale {
+		scope.Transaction = Sale
+	} else if r.ForRent {
+		scope.Transaction = Rent
+	}
+
+	return scope
+}
+
+// canonicalUrl drops the scheme, fragment and trailing slash of a URL, so the
+// same page reached in slightly different ways yields the same key. The path
+// is kept escaped, as written in the URL, like migration 0002 keyed the real
+// estates already in the graph.
+func canonicalUrl(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	canonical := strings.ToLower(u.Host) + u.EscapedPath()
+	if u.RawQuery != "" {
+		canonical += "?" + u.RawQuery
+	}
 
-	return err
+	return strings.TrimSuffix(canonical, "/")
 }
@@ -0,0 +1,39 @@
+package contracts_test
+
+import (
+	"errors"
+	"testing"
+
+	"baia/internal/contracts"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		estate contracts.RealEstate
+		want   string
+		err    error
+	}{
+		{"agency and code", contracts.RealEstate{Agency: "Imobiliária Pampa", Code: " AP123 ", Url: "https://pampa.com.br/ap123"}, "imobiliariapampa:AP123", nil},
+		{"url without scheme, fragment and trailing slash", contracts.RealEstate{Agency: "Pampa", Url: "https://Pampa.com.br/imovel/12/#fotos"}, "pampa:url:pampa.com.br/imovel/12", nil},
+		{"url with query", contracts.RealEstate{Agency: "Pampa", Url: "http://pampa.com.br/imovel?id=12"}, "pampa:url:pampa.com.br/imovel?id=12", nil},
+		{"url with an encoded path", contracts.RealEstate{Agency: "Pampa", Url: "https://pampa.com.br/im%C3%B3vel/casa%20com%20p%C3%A1tio%2F12"}, "pampa:url:pampa.com.br/im%C3%B3vel/casa%20com%20p%C3%A1tio%2F12", nil},
+		{"url with an unencoded path", contracts.RealEstate{Agency: "Pampa", Url: "https://pampa.com.br/imóvel/12"}, "pampa:url:pampa.com.br/im%C3%B3vel/12", nil},
+		{"no agency", contracts.RealEstate{Code: "AP123"}, "", contracts.ErrNoIdentity},
+		{"no code nor url", contracts.RealEstate{Agency: "Pampa"}, "", contracts.ErrNoIdentity},
+		{"relative url", contracts.RealEstate{Agency: "Pampa", Url: "/imovel/12"}, "", contracts.ErrNoIdentity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.estate.Key()
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Key() error = %v, want %v", err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("Key() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"baia/pkg/page"
+)
+
+// ErrNotFound is returned when a real estate does not exist in the repository.
+var ErrNotFound = errors.New("real estate not found")
+
+// SaveReport receives the real estates a repository wrote, or failed to write,
+// together with the error of the write. Repositories may buffer saves, so this
+// is where callers learn the outcome of each real estate.
+type SaveReport func(items []RealEstate, err error)
+
+// PricePoint is a price observed for a real estate.
+type PricePoint struct {
+	Value     int       `json:"value"`
+	ForSale   bool      `json:"forSale"`
+	ForRent   bool      `json:"forRent"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SearchFilter narrows a search of real estates. Zero values match anything.
+type SearchFilter struct {
+	Agency          string
+	City            string
+	Type            string
+	Transaction     string
+	MinPrice        int
+	MaxPrice        int
+	MinBedrooms     int
+	IncludeDelisted bool
+	Limit           int
+	Offset          int
+}
+
+// AgencyStats counts the real estates of an agency in the repository.
+type AgencyStats struct {
+	Agency   string `json:"agency"`
+	Listings int    `json:"listings"`
+	ForSale  int    `json:"forSale"`
+	ForRent  int    `json:"forRent"`
+	Delisted int    `json:"delisted"`
+}
+
+// DefaultSearchLimit is the number of results of a search without a limit.
+const DefaultSearchLimit = 50
+
+// RealEstateRepository persists real estates and their price history.
+type RealEstateRepository interface {
+	// Save stores the real estate, possibly buffering it until Flush.
+	Save(ctx context.Context, r RealEstate) error
+	// Flush writes every buffered real estate.
+	Flush(ctx context.Context) error
+	// Get returns the real estate with the given id, or ErrNotFound.
+	Get(ctx context.Context, id string) (RealEstate, error)
+	// Search returns the real estates matching the filter, ordered by key.
+	Search(ctx context.Context, filter SearchFilter) ([]RealEstate, error)
+	// PriceHistory returns the prices of the real estate from the oldest to the newest.
+	PriceHistory(ctx context.Context, id string) ([]PricePoint, error)
+	// Stats counts the real estates of every agency, ordered by agency.
+	Stats(ctx context.Context) ([]AgencyStats, error)
+	// MarkDelisted flags the real estates of the scope that the crawl did not see,
+	// returning how many were flagged.
+	MarkDelisted(ctx context.Context, scope Hints, crawlID string) (int64, error)
+	// Validators returns, by URL, the validators of the detail pages the real
+	// estates were last scraped from.
+	Validators(ctx context.Context) (map[string]page.Validators, error)
+	// Touch records that the crawl saw the real estates of the URLs unchanged,
+	// without saving them again, and returns them.
+	Touch(ctx context.Context, urls []string, crawlID string) ([]RealEstate, error)
+	// CrawlChanges returns the URLs of the real estates the crawl created and
+	// of the ones whose price it changed.
+	CrawlChanges(ctx context.Context, crawlID string) (CrawlChanges, error)
+	// SaveCrawlRun stores the report of a crawl, replacing any earlier report
+	// of the same crawl.
+	SaveCrawlRun(ctx context.Context, report CrawlReport) error
+}
@@ -6,6 +6,19 @@ import (
 	"github.com/gocolly/colly/v2"
 )
 
+// ScraperOptions carries the per-source settings shared by every scraper implementation.
+type ScraperOptions struct {
+	// Hints take precedence over what a scraper infers from the page or the URL.
+	Hints Hints
+	// StructuredDataFallback fills the fields the selectors left empty with the
+	// schema.org data embedded in the detail page.
+	StructuredDataFallback bool
+	// Collector holds the options applied to every collector the scraper creates.
+	Collector []func(c *colly.Collector)
+	// Tracer, when set, receives every field extraction step of the scraper.
+	Tracer Tracer
+}
+
 type RealEstateScraper interface {
 	GetRealEstateUrls(ctx context.Context, url string) ([]string, []string)
 	GetRealEstate(ctx context.Context, ch chan RealEstate, re *RealEstate)
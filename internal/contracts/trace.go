@@ -0,0 +1,52 @@
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoValue is traced for matches that leave the field without a value, which
+// is a missing field rather than one that failed to parse.
+var ErrNoValue = errors.New("matched element has no value")
+
+// TraceStep is one match of the selector of a field and what the setter made
+// of it.
+type TraceStep struct {
+	// Url is the page the field was extracted from.
+	Url      string `json:"-"`
+	Field    string `json:"field"`
+	Selector string `json:"selector"`
+	// Raw is the text matched by the selector, as given to the setter.
+	Raw string `json:"raw"`
+	// Value is the field of the real estate after the setter ran.
+	Value any   `json:"value"`
+	Err   error `json:"-"`
+}
+
+// Tracer receives the extraction steps of a scraper, so an empty field can be
+// told apart from a selector that did not match or a value that did not parse.
+// Scrapers declare their fields before the page is visited and report every
+// match while it is parsed.
+type Tracer interface {
+	// Field declares the selector a field is extracted with. An empty selector
+	// tells the scraper does not extract the field.
+	Field(name, selector string)
+	// Step reports a match of the selector of a field.
+	Step(step TraceStep)
+}
+
+// FieldValue returns the field of a real estate by the name of its json tag,
+// which is also the name used by the selectors blocks of the sources file.
+func FieldValue(v any, name string) any {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	fields := make(map[string]any)
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil
+	}
+
+	return fields[name]
+}
@@ -0,0 +1,308 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Severity tells how wrong a real estate that breaks a rule is.
+type Severity int
+
+const (
+	// SeverityInfo is a field that is merely missing.
+	SeverityInfo Severity = iota
+	// SeverityWarning is a field that is likely wrong or that most listings have.
+	SeverityWarning
+	// SeverityError is a field that cannot be right, usually a parsing bug.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*s = SeverityInfo
+	case "warning":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		return fmt.Errorf("invalid severity %q", text)
+	}
+	return nil
+}
+
+// Violation is a rule broken by a real estate.
+type Violation struct {
+	Rule     string   `json:"rule"`
+	Field    string   `json:"field"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Violations are the rules broken by a real estate.
+type Violations []Violation
+
+// Severity returns the highest severity of the violations, or -1 when there
+// are none.
+func (v Violations) Severity() Severity {
+	highest := Severity(-1)
+	for _, violation := range v {
+		highest = max(highest, violation.Severity)
+	}
+	return highest
+}
+
+// Invalid reports whether any violation is an error.
+func (v Violations) Invalid() bool {
+	return v.Severity() >= SeverityError
+}
+
+func (v Violations) String() string {
+	messages := make([]string, 0, len(v))
+	for _, violation := range v {
+		messages = append(messages, violation.Severity.String()+": "+violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Rule checks one field of a real estate. Check returns why the real estate
+// breaks the rule, or an empty string when it does not.
+type Rule struct {
+	Name     string
+	Field    string
+	Severity Severity
+	Check    func(r RealEstate) string
+}
+
+// Validate returns the rules the real estate breaks.
+func (r RealEstate) Validate(rules []Rule) Violations {
+	var violations Violations
+
+	for _, rule := range rules {
+		if message := rule.Check(r); message != "" {
+			violations = append(violations, Violation{
+				Rule:     rule.Name,
+				Field:    rule.Field,
+				Severity: rule.Severity,
+				Message:  message,
+			})
+		}
+	}
+
+	return violations
+}
+
+// ParseViolations returns the fields of the real estate whose text failed to
+// parse, as traced in the steps of its page, and that no other match filled.
+func ParseViolations(r RealEstate, steps []TraceStep) Violations {
+	var violations Violations
+	reported := make(map[string]bool)
+
+	for _, step := range steps {
+		if step.Err == nil || errors.Is(step.Err, ErrNoValue) || reported[step.Field] {
+			continue
+		}
+
+		if !empty(FieldValue(r, step.Field)) {
+			continue
+		}
+
+		reported[step.Field] = true
+		violations = append(violations, Violation{
+			Rule:     "parse",
+			Field:    step.Field,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s %q could not be parsed: %v", step.Field, strings.TrimSpace(step.Raw), step.Err),
+		})
+	}
+
+	return violations
+}
+
+// empty reports whether a field value, as decoded from JSON, is unset.
+func empty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []any:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// Plausible bounds of the fields of a real estate in Rio Grande do Sul.
+// Prices per m² are in R$, monthly for rentals; land is sold by the m² far
+// cheaper than buildings, and rural land and large leases for cents.
+const (
+	MinSalePricePerMeter     = 100.0
+	MinLandSalePricePerMeter = 0.05
+	MaxSalePricePerMeter     = 100_000.0
+	MinRentPricePerMeter     = 0.005
+	MaxRentPricePerMeter     = 1_000.0
+	MaxRooms                 = 20
+	MaxGarageSpaces          = 50
+	MinArea                  = 10
+	MaxArea                  = 10_000_000
+	MinYearBuilt             = 1800
+)
+
+// required is a rule broken when the field of the real estate is empty.
+func required(field string, severity Severity, missing func(r RealEstate) bool) Rule {
+	return Rule{
+		Name:     "required",
+		Field:    field,
+		Severity: severity,
+		Check: func(r RealEstate) string {
+			if missing(r) {
+				return field + " is missing"
+			}
+			return ""
+		},
+	}
+}
+
+// DefaultRules are the rules real estates are validated with: the fields
+// every listing has, and the ranges outside of which a value was most likely
+// parsed wrong.
+var DefaultRules = []Rule{
+	required("url", SeverityError, func(r RealEstate) bool { return r.Url == "" }),
+	required("agency", SeverityError, func(r RealEstate) bool { return r.Agency == "" }),
+	required("price", SeverityWarning, func(r RealEstate) bool { return r.Price <= 0 && !r.PriceOnRequest }),
+	required("type", SeverityWarning, func(r RealEstate) bool { return r.Type == "" }),
+	required("transaction", SeverityWarning, func(r RealEstate) bool { return !r.ForSale && !r.ForRent }),
+	required("city", SeverityWarning, func(r RealEstate) bool { return r.City == "" }),
+	required("area", SeverityInfo, func(r RealEstate) bool { return r.Area <= 0 }),
+	required("code", SeverityInfo, func(r RealEstate) bool { return r.Code == "" }),
+	required("name", SeverityInfo, func(r RealEstate) bool { return r.Name == "" }),
+	{
+		Name:     "pricePerMeter",
+		Field:    "price",
+		Severity: SeverityError,
+		Check: func(r RealEstate) string {
+			if r.Price <= 0 || r.Area <= 0 {
+				return ""
+			}
+
+			perMeter := float64(r.Price) / float64(r.Area)
+			low, high, transaction := MinSalePricePerMeter, MaxSalePricePerMeter, "sale"
+
+			switch {
+			case r.ForRent:
+				low, high, transaction = MinRentPricePerMeter, MaxRentPricePerMeter, "rent"
+			case r.Type == Land:
+				low = MinLandSalePricePerMeter
+			}
+
+			if perMeter < low || perMeter > high {
+				return fmt.Sprintf("price of R$ %.2f per m² is out of the plausible range for a %s, R$ %g to %g", perMeter, transaction, low, high)
+			}
+			return ""
+		},
+	},
+	{
+		Name:     "range",
+		Field:    "bedrooms",
+		Severity: SeverityError,
+		Check: func(r RealEstate) string {
+			if r.Bedrooms < 0 || r.Bedrooms > MaxRooms {
+				return fmt.Sprintf("%d bedrooms is out of the plausible range, 0 to %d", r.Bedrooms, MaxRooms)
+			}
+			return ""
+		},
+	},
+	{
+		Name:     "range",
+		Field:    "bathrooms",
+		Severity: SeverityError,
+		Check: func(r RealEstate) string {
+			if r.Bathrooms < 0 || r.Bathrooms > MaxRooms {
+				return fmt.Sprintf("%d bathrooms is out of the plausible range, 0 to %d", r.Bathrooms, MaxRooms)
+			}
+			return ""
+		},
+	},
+	{
+		Name:     "range",
+		Field:    "garageSpaces",
+		Severity: SeverityWarning,
+		Check: func(r RealEstate) string {
+			if r.GarageSpaces < 0 || r.GarageSpaces > MaxGarageSpaces {
+				return fmt.Sprintf("%d garage spaces is out of the plausible range, 0 to %d", r.GarageSpaces, MaxGarageSpaces)
+			}
+			return ""
+		},
+	},
+	{
+		Name:     "range",
+		Field:    "area",
+		Severity: SeverityError,
+		Check: func(r RealEstate) string {
+			if r.Area != 0 && (r.Area < MinArea || r.Area > MaxArea) {
+				return fmt.Sprintf("area of %d m² is out of the plausible range, %d to %d", r.Area, MinArea, MaxArea)
+			}
+			return ""
+		},
+	},
+	{
+		Name:     "range",
+		Field:    "yearBuilt",
+		Severity: SeverityError,
+		Check: func(r RealEstate) string {
+			// Listings of buildings under construction show the year they
+			// will be delivered.
+			latest := time.Now().Year() + 5
+
+			if r.YearBuilt != 0 && (r.YearBuilt < MinYearBuilt || r.YearBuilt > latest) {
+				return fmt.Sprintf("year built %d is out of the plausible range, %d to %d", r.YearBuilt, MinYearBuilt, latest)
+			}
+			return ""
+		},
+	},
+}
+
+// InvalidPolicy is what is done with the real estates that break a rule of
+// error severity.
+type InvalidPolicy string
+
+const (
+	// SaveInvalid saves them like valid ones.
+	SaveInvalid InvalidPolicy = "save"
+	// QuarantineInvalid keeps them out of the graph, for review.
+	QuarantineInvalid InvalidPolicy = "quarantine"
+	// DropInvalid discards them.
+	DropInvalid InvalidPolicy = "drop"
+)
+
+// Valid reports whether the policy is a known one.
+func (p InvalidPolicy) Valid() bool {
+	switch p {
+	case SaveInvalid, QuarantineInvalid, DropInvalid:
+		return true
+	default:
+		return false
+	}
+}
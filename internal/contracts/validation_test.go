@@ -0,0 +1,146 @@
+package contracts_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"baia/internal/contracts"
+)
+
+// broken returns the names of the rules of DefaultRules the real estate breaks
+// on the field.
+func broken(r contracts.RealEstate, field string) []string {
+	var names []string
+	for _, violation := range r.Validate(contracts.DefaultRules) {
+		if violation.Field == field {
+			names = append(names, violation.Rule)
+		}
+	}
+	return names
+}
+
+func TestPricePerMeter(t *testing.T) {
+	const hectare = 10_000
+
+	tests := []struct {
+		name    string
+		estate  contracts.RealEstate
+		invalid bool
+	}{
+		{"rural land at R$ 0,80 per m²", contracts.RealEstate{Type: contracts.Land, ForSale: true, Price: 800_000, Area: 100 * hectare}, false},
+		{"land at the lowest price", contracts.RealEstate{Type: contracts.Land, ForSale: true, Price: 5_000, Area: 10 * hectare}, false},
+		{"land under the lowest price", contracts.RealEstate{Type: contracts.Land, ForSale: true, Price: 4_999, Area: 10 * hectare}, true},
+		{"house at the lowest price", contracts.RealEstate{Type: contracts.House, ForSale: true, Price: 10_000, Area: 100}, false},
+		{"house under the lowest price", contracts.RealEstate{Type: contracts.House, ForSale: true, Price: 9_999, Area: 100}, true},
+		{"apartment at the highest price", contracts.RealEstate{Type: contracts.Apartment, ForSale: true, Price: 10_000_000, Area: 100}, false},
+		{"apartment over the highest price", contracts.RealEstate{Type: contracts.Apartment, ForSale: true, Price: 10_000_001, Area: 100}, true},
+		{"lease of a farm at R$ 0,01 per m²", contracts.RealEstate{Type: contracts.Land, ForRent: true, Price: 5_000, Area: 50 * hectare}, false},
+		{"rent at the lowest price", contracts.RealEstate{Type: contracts.Land, ForRent: true, Price: 500, Area: 10 * hectare}, false},
+		{"rent under the lowest price", contracts.RealEstate{Type: contracts.Land, ForRent: true, Price: 499, Area: 10 * hectare}, true},
+		{"rent at the highest price", contracts.RealEstate{Type: contracts.Commercial, ForRent: true, Price: 50_000, Area: 50}, false},
+		{"rent over the highest price", contracts.RealEstate{Type: contracts.Commercial, ForRent: true, Price: 50_001, Area: 50}, true},
+		{"price with no area", contracts.RealEstate{Type: contracts.House, ForSale: true, Price: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := slices.Contains(broken(tt.estate, "price"), "pricePerMeter")
+			if invalid != tt.invalid {
+				t.Errorf("pricePerMeter broken = %v, want %v", invalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	// valid returns a real estate that breaks no rule, for the cases to break one.
+	valid := func(change func(r *contracts.RealEstate)) contracts.RealEstate {
+		r := contracts.RealEstate{
+			Url:          "https://pampa.com.br/imovel/12",
+			Agency:       "Pampa",
+			Code:         "12",
+			Name:         "Casa no Centro",
+			Type:         contracts.House,
+			ForSale:      true,
+			Price:        450_000,
+			Area:         120,
+			Bedrooms:     3,
+			Bathrooms:    2,
+			GarageSpaces: 1,
+			City:         "Santo Ângelo",
+			YearBuilt:    2010,
+		}
+		if change != nil {
+			change(&r)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		estate   contracts.RealEstate
+		field    string
+		rule     string
+		severity contracts.Severity
+	}{
+		{"no url", valid(func(r *contracts.RealEstate) { r.Url = "" }), "url", "required", contracts.SeverityError},
+		{"no agency", valid(func(r *contracts.RealEstate) { r.Agency = "" }), "agency", "required", contracts.SeverityError},
+		{"no price", valid(func(r *contracts.RealEstate) { r.Price = 0 }), "price", "required", contracts.SeverityWarning},
+		{"no type", valid(func(r *contracts.RealEstate) { r.Type = "" }), "type", "required", contracts.SeverityWarning},
+		{"no transaction", valid(func(r *contracts.RealEstate) { r.ForSale = false }), "transaction", "required", contracts.SeverityWarning},
+		{"no city", valid(func(r *contracts.RealEstate) { r.City = "" }), "city", "required", contracts.SeverityWarning},
+		{"no area", valid(func(r *contracts.RealEstate) { r.Area = 0 }), "area", "required", contracts.SeverityInfo},
+		{"no code", valid(func(r *contracts.RealEstate) { r.Code = "" }), "code", "required", contracts.SeverityInfo},
+		{"no name", valid(func(r *contracts.RealEstate) { r.Name = "" }), "name", "required", contracts.SeverityInfo},
+		{"too many bedrooms", valid(func(r *contracts.RealEstate) { r.Bedrooms = contracts.MaxRooms + 1 }), "bedrooms", "range", contracts.SeverityError},
+		{"negative bathrooms", valid(func(r *contracts.RealEstate) { r.Bathrooms = -1 }), "bathrooms", "range", contracts.SeverityError},
+		{"too many garage spaces", valid(func(r *contracts.RealEstate) { r.GarageSpaces = contracts.MaxGarageSpaces + 1 }), "garageSpaces", "range", contracts.SeverityWarning},
+		{"area too small", valid(func(r *contracts.RealEstate) { r.Area, r.Price = contracts.MinArea-1, 5_000 }), "area", "range", contracts.SeverityError},
+		{"area too large", valid(func(r *contracts.RealEstate) {
+			r.Type, r.Area, r.Price = contracts.Land, contracts.MaxArea+1, 5_000_000
+		}), "area", "range", contracts.SeverityError},
+		{"built too early", valid(func(r *contracts.RealEstate) { r.YearBuilt = contracts.MinYearBuilt - 1 }), "yearBuilt", "range", contracts.SeverityError},
+		{"built too far ahead", valid(func(r *contracts.RealEstate) { r.YearBuilt = time.Now().Year() + 6 }), "yearBuilt", "range", contracts.SeverityError},
+	}
+
+	if violations := valid(nil).Validate(contracts.DefaultRules); len(violations) > 0 {
+		t.Fatalf("valid real estate breaks %v", violations)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := tt.estate.Validate(contracts.DefaultRules)
+
+			if len(violations) != 1 {
+				t.Fatalf("Validate() = %v, want only %s of %s", violations, tt.rule, tt.field)
+			}
+
+			v := violations[0]
+			if v.Field != tt.field || v.Rule != tt.rule || v.Severity != tt.severity {
+				t.Errorf("Validate() = %s %s %s, want %s %s %s", v.Severity, v.Rule, v.Field, tt.severity, tt.rule, tt.field)
+			}
+			if v.Message == "" {
+				t.Error("violation has no message")
+			}
+		})
+	}
+}
+
+func TestDefaultRulesExceptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		estate contracts.RealEstate
+	}{
+		{"price on request", contracts.RealEstate{Url: "https://a.com.br/1", Agency: "A", Code: "1", Name: "Casa", Type: contracts.House, ForSale: true, City: "Ijuí", Area: 100, PriceOnRequest: true}},
+		{"delivered within five years", contracts.RealEstate{Url: "https://a.com.br/1", Agency: "A", Code: "1", Name: "Apto", Type: contracts.Apartment, ForSale: true, City: "Ijuí", Area: 80, Price: 600_000, YearBuilt: time.Now().Year() + 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if violations := tt.estate.Validate(contracts.DefaultRules); len(violations) > 0 {
+				t.Errorf("Validate() = %v, want none", violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package crawl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"baia/internal/contracts"
+
+	"github.com/ricardocastanho/scrapify"
+)
+
+// ErrNoCheckpoint is returned when there is no checkpoint to resume.
+var ErrNoCheckpoint = errors.New("no checkpoint to resume")
+
+const checkpointExt = ".jsonl"
+
+// checkpointEntry is a line of the checkpoint file: either a listing page with
+// the listings and next pages found on it, or a listing saved to the graph.
+type checkpointEntry struct {
+	Page      string           `json:"page,omitempty"`
+	Listings  []string         `json:"listings,omitempty"`
+	NextPages []string         `json:"nextPages,omitempty"`
+	Listing   string           `json:"listing,omitempty"`
+	Scope     *contracts.Hints `json:"scope,omitempty"`
+}
+
+type frontier struct {
+	listings  []string
+	nextPages []string
+}
+
+// Checkpoint persists the frontier of a crawl run, so that a run stopped by a
+// crash or a timeout can be resumed without fetching again the listing pages
+// already scraped and the listings already saved.
+// It is an append only JSON Lines file named after the run id, which survives
+// the process dying in the middle of a write.
+type Checkpoint struct {
+	RunID string
+
+	path      string
+	mutex     sync.Mutex
+	file      *os.File
+	pages     map[string]frontier
+	completed map[string]contracts.Hints
+}
+
+// OpenCheckpoint opens the checkpoint of the run in dir, loading what an
+// earlier attempt of the run recorded, or creates it.
+func OpenCheckpoint(dir, runID string) (*Checkpoint, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	c := &Checkpoint{
+		RunID:     runID,
+		path:      filepath.Join(dir, runID+checkpointExt),
+		pages:     make(map[string]frontier),
+		completed: make(map[string]contracts.Hints),
+	}
+
+	torn, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	c.file = file
+
+	if torn {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to repair checkpoint: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// LatestCheckpoint returns the run id of the most recent checkpoint in dir.
+// Run ids are time sortable, and checkpoints are removed once their run completes.
+func LatestCheckpoint(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+checkpointExt))
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "", ErrNoCheckpoint
+	}
+
+	slices.Sort(matches)
+
+	return strings.TrimSuffix(filepath.Base(matches[len(matches)-1]), checkpointExt), nil
+}
+
+// load replays the checkpoint file, if any, and reports whether its last line
+// was cut short, so the next entry does not get appended to it.
+func (c *Checkpoint) load() (bool, error) {
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		var entry checkpointEntry
+
+		// A line cut short by a crash is the last one, and only loses that entry.
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+
+		switch {
+		case entry.Page != "":
+			c.pages[entry.Page] = frontier{listings: entry.Listings, nextPages: entry.NextPages}
+		case entry.Listing != "" && entry.Scope != nil:
+			c.completed[entry.Listing] = *entry.Scope
+		}
+	}
+
+	return len(content) > 0 && content[len(content)-1] != '\n', nil
+}
+
+func (c *Checkpoint) append(entry checkpointEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.file.Write(append(line, '\n'))
+
+	return err
+}
+
+// Page returns what a listing page had when it was scraped by the run.
+func (c *Checkpoint) Page(url string) ([]string, []string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	page, ok := c.pages[url]
+
+	return page.listings, page.nextPages, ok
+}
+
+// RecordPage records the listings and next pages found on a listing page.
+func (c *Checkpoint) RecordPage(url string, listings, nextPages []string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pages[url] = frontier{listings: listings, nextPages: nextPages}
+
+	return c.append(checkpointEntry{Page: url, Listings: listings, NextPages: nextPages})
+}
+
+// Completed reports whether the listing was saved by the run.
+func (c *Checkpoint) Completed(url string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, ok := c.completed[url]
+
+	return ok
+}
+
+// Complete records a listing saved to the graph.
+func (c *Checkpoint) Complete(r contracts.RealEstate) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	scope := r.Scope()
+	c.completed[r.Url] = scope
+
+	return c.append(checkpointEntry{Listing: r.Url, Scope: &scope})
+}
+
+// Scopes returns the scopes of the listings saved by the run.
+func (c *Checkpoint) Scopes() []contracts.Hints {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	scopes := make([]contracts.Hints, 0, len(c.completed))
+	for _, scope := range c.completed {
+		scopes = append(scopes, scope)
+	}
+
+	return scopes
+}
+
+// Summary returns how many listing pages and listings the run has recorded.
+func (c *Checkpoint) Summary() (pages, listings int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.pages), len(c.completed)
+}
+
+// Close closes the checkpoint file, keeping it for a later resume.
+func (c *Checkpoint) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.file.Close()
+}
+
+// Remove closes and deletes the checkpoint, once its run is complete.
+func (c *Checkpoint) Remove() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(c.path)
+}
+
+// Strategies wraps the scraper of every strategy, so that listing pages
+// recorded by the run are not fetched again and saved listings are skipped.
+func (c *Checkpoint) Strategies(strategies []scrapify.ScraperStrategy[contracts.RealEstate]) []scrapify.ScraperStrategy[contracts.RealEstate] {
+	wrapped := make([]scrapify.ScraperStrategy[contracts.RealEstate], 0, len(strategies))
+
+	for _, strategy := range strategies {
+		wrapped = append(wrapped, scrapify.ScraperStrategy[contracts.RealEstate]{
+			Scraper: &checkpointScraper{checkpoint: c, scraper: strategy.Scraper},
+			Url:     strategy.Url,
+		})
+	}
+
+	return wrapped
+}
+
+type checkpointScraper struct {
+	checkpoint *Checkpoint
+	scraper    scrapify.IScraper[contracts.RealEstate]
+}
+
+func (s *checkpointScraper) GetUrls(ctx context.Context, url string) ([]string, []string) {
+	if urls, nextPages, ok := s.checkpoint.Page(url); ok {
+		return urls, nextPages
+	}
+
+	urls, nextPages := s.scraper.GetUrls(ctx, url)
+
+	// Pages cut short or that failed to load are fetched again on resume.
+	if ctx.Err() != nil || len(urls)+len(nextPages) == 0 {
+		return urls, nextPages
+	}
+
+	// A page that fails to be recorded is only fetched again on resume.
+	_ = s.checkpoint.RecordPage(url, urls, nextPages)
+
+	return urls, nextPages
+}
+
+func (s *checkpointScraper) GetData(ctx context.Context, ch chan<- contracts.RealEstate, data *contracts.RealEstate, url string) {
+	if s.checkpoint.Completed(url) {
+		return
+	}
+
+	s.scraper.GetData(ctx, ch, data, url)
+}
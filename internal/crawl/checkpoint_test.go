@@ -0,0 +1,164 @@
+package crawl_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"baia/internal/contracts"
+	"baia/internal/crawl"
+)
+
+const (
+	page    = `{"page":"https://a.com.br/venda","listings":["https://a.com.br/1","https://a.com.br/2"],"nextPages":["https://a.com.br/venda?p=2"]}`
+	listing = `{"listing":"https://a.com.br/1","scope":{"agency":"Agência A","transaction":"sale"}}`
+)
+
+func TestOpenCheckpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   *string
+		pages     int
+		listings  int
+		completed []string
+	}{
+		{"no checkpoint", nil, 0, 0, nil},
+		{"empty checkpoint", ptr(""), 0, 0, nil},
+		{"page and listing", ptr(page + "\n" + listing + "\n"), 1, 1, []string{"https://a.com.br/1"}},
+		{"torn last line", ptr(page + "\n" + listing[:30]), 1, 0, nil},
+		{"torn line repaired before a later entry", ptr(page[:40] + "\n" + listing + "\n"), 0, 1, []string{"https://a.com.br/1"}},
+		{"listing without scope", ptr(`{"listing":"https://a.com.br/1"}` + "\n"), 0, 0, nil},
+		{"blank lines", ptr("\n\n" + listing + "\n\n"), 0, 1, []string{"https://a.com.br/1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.content != nil {
+				if err := os.WriteFile(filepath.Join(dir, "run.jsonl"), []byte(*tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			checkpoint, err := crawl.OpenCheckpoint(dir, "run")
+			if err != nil {
+				t.Fatalf("OpenCheckpoint() error = %v", err)
+			}
+			defer checkpoint.Close()
+
+			if pages, listings := checkpoint.Summary(); pages != tt.pages || listings != tt.listings {
+				t.Errorf("Summary() = %d pages and %d listings, want %d and %d", pages, listings, tt.pages, tt.listings)
+			}
+
+			for _, url := range tt.completed {
+				if !checkpoint.Completed(url) {
+					t.Errorf("Completed(%s) = false, want true", url)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpointPage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.jsonl"), []byte(page+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := crawl.OpenCheckpoint(dir, "run")
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+	defer checkpoint.Close()
+
+	listings, nextPages, ok := checkpoint.Page("https://a.com.br/venda")
+	if !ok {
+		t.Fatal("Page() found no page")
+	}
+	if want := []string{"https://a.com.br/1", "https://a.com.br/2"}; !slices.Equal(listings, want) {
+		t.Errorf("Page() listings = %v, want %v", listings, want)
+	}
+	if want := []string{"https://a.com.br/venda?p=2"}; !slices.Equal(nextPages, want) {
+		t.Errorf("Page() next pages = %v, want %v", nextPages, want)
+	}
+
+	if _, _, ok := checkpoint.Page("https://a.com.br/aluguel"); ok {
+		t.Error("Page() found a page that was not recorded")
+	}
+}
+
+// TestCheckpointResumeTorn appends to a checkpoint whose last line was cut
+// short, and checks that the new entry survives the next resume.
+func TestCheckpointResumeTorn(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "run.jsonl"), []byte(page+"\n"+listing[:30]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := crawl.OpenCheckpoint(dir, "run")
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+
+	estate := contracts.RealEstate{Url: "https://a.com.br/2", Agency: "Agência A", ForRent: true}
+	if err := checkpoint.Complete(estate); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if err := checkpoint.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := crawl.OpenCheckpoint(dir, "run")
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() again error = %v", err)
+	}
+	defer resumed.Close()
+
+	if pages, listings := resumed.Summary(); pages != 1 || listings != 1 {
+		t.Errorf("Summary() = %d pages and %d listings, want 1 and 1", pages, listings)
+	}
+	if !resumed.Completed(estate.Url) {
+		t.Errorf("Completed(%s) = false, want true", estate.Url)
+	}
+	if want := []contracts.Hints{estate.Scope()}; !slices.Equal(resumed.Scopes(), want) {
+		t.Errorf("Scopes() = %v, want %v", resumed.Scopes(), want)
+	}
+}
+
+func TestLatestCheckpoint(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+		err   error
+	}{
+		{"no checkpoint", nil, "", crawl.ErrNoCheckpoint},
+		{"other files only", []string{"notes.txt"}, "", crawl.ErrNoCheckpoint},
+		{"latest run id", []string{"20240301T000000Z-aaaa.jsonl", "20240310T000000Z-bbbb.jsonl", "20240305T000000Z-cccc.jsonl"}, "20240310T000000Z-bbbb", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := crawl.LatestCheckpoint(dir)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("LatestCheckpoint() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestCheckpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,141 @@
+package crawl
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"baia/internal/contracts"
+
+	"github.com/ricardocastanho/scrapify"
+)
+
+// DrainSummary counts what the scrapers of a drain did.
+type DrainSummary struct {
+	// Pages and Listings are the listing pages and detail pages fully scraped.
+	Pages    int64
+	Listings int64
+	// Skipped are the visits not started because the crawl was stopping.
+	Skipped int64
+	// Abandoned are the visits in flight when the work context was done.
+	Abandoned int64
+}
+
+// Drain wraps scrapers so that, once the stop context is done, they start no
+// new page visits, while the visits already in flight keep running with the
+// work context until they finish or it is done too.
+type Drain struct {
+	stop context.Context
+	work context.Context
+	wg   sync.WaitGroup
+
+	pages     atomic.Int64
+	listings  atomic.Int64
+	skipped   atomic.Int64
+	abandoned atomic.Int64
+}
+
+// NewDrain creates a drain. The work context should outlive the stop one by
+// the grace period given to the visits in flight.
+func NewDrain(stop, work context.Context) *Drain {
+	return &Drain{stop: stop, work: work}
+}
+
+// Strategies wraps the scraper of every strategy.
+func (d *Drain) Strategies(strategies []scrapify.ScraperStrategy[contracts.RealEstate]) []scrapify.ScraperStrategy[contracts.RealEstate] {
+	wrapped := make([]scrapify.ScraperStrategy[contracts.RealEstate], 0, len(strategies))
+
+	for _, strategy := range strategies {
+		wrapped = append(wrapped, scrapify.ScraperStrategy[contracts.RealEstate]{
+			Scraper: &drainingScraper{drain: d, scraper: strategy.Scraper},
+			Url:     strategy.Url,
+		})
+	}
+
+	return wrapped
+}
+
+// Wait blocks until the visits in flight are over.
+func (d *Drain) Wait() {
+	d.wg.Wait()
+}
+
+// Summary returns the counts of the drain so far.
+func (d *Drain) Summary() DrainSummary {
+	return DrainSummary{
+		Pages:     d.pages.Load(),
+		Listings:  d.listings.Load(),
+		Skipped:   d.skipped.Load(),
+		Abandoned: d.abandoned.Load(),
+	}
+}
+
+// start registers a visit, unless the crawl is stopping.
+func (d *Drain) start() bool {
+	if d.stop.Err() != nil {
+		d.skipped.Add(1)
+		return false
+	}
+
+	d.wg.Add(1)
+	return true
+}
+
+type drainingScraper struct {
+	drain   *Drain
+	scraper scrapify.IScraper[contracts.RealEstate]
+}
+
+func (s *drainingScraper) GetUrls(ctx context.Context, url string) ([]string, []string) {
+	d := s.drain
+
+	if !d.start() {
+		return nil, nil
+	}
+	defer d.wg.Done()
+
+	urls, nextPages := s.scraper.GetUrls(d.work, url)
+
+	if d.work.Err() != nil {
+		d.abandoned.Add(1)
+		return nil, nil
+	}
+
+	d.pages.Add(1)
+
+	return urls, nextPages
+}
+
+func (s *drainingScraper) GetData(ctx context.Context, ch chan<- contracts.RealEstate, data *contracts.RealEstate, url string) {
+	d := s.drain
+
+	if !d.start() {
+		return
+	}
+	defer d.wg.Done()
+
+	// Scrapers cut their extraction short when the context is done, so what
+	// they send is only forwarded when the work context outlived the visit.
+	extracted := make(chan contracts.RealEstate, 1)
+
+	s.scraper.GetData(d.work, extracted, data, url)
+
+	select {
+	case result := <-extracted:
+		if d.work.Err() != nil {
+			d.abandoned.Add(1)
+			return
+		}
+
+		select {
+		case ch <- result:
+			d.listings.Add(1)
+		case <-d.work.Done():
+			d.abandoned.Add(1)
+		}
+	default:
+		if d.work.Err() != nil {
+			d.abandoned.Add(1)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package crawl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"baia/internal/contracts"
+
+	"github.com/ricardocastanho/scrapify"
+)
+
+// coverageFields are the fields whose coverage a report measures, by the name
+// of their json tag.
+var coverageFields = []struct {
+	name   string
+	filled func(r contracts.RealEstate) bool
+}{
+	{"code", func(r contracts.RealEstate) bool { return r.Code != "" }},
+	{"name", func(r contracts.RealEstate) bool { return r.Name != "" }},
+	{"description", func(r contracts.RealEstate) bool { return r.Description != "" }},
+	{"price", func(r contracts.RealEstate) bool { return r.Price > 0 }},
+	{"bedrooms", func(r contracts.RealEstate) bool { return r.Bedrooms > 0 }},
+	{"bathrooms", func(r contracts.RealEstate) bool { return r.Bathrooms > 0 }},
+	{"area", func(r contracts.RealEstate) bool { return r.Area > 0 }},
+	{"garageSpaces", func(r contracts.RealEstate) bool { return r.GarageSpaces > 0 }},
+	{"city", func(r contracts.RealEstate) bool { return r.City != "" }},
+	{"district", func(r contracts.RealEstate) bool { return r.District != "" }},
+	{"yearBuilt", func(r contracts.RealEstate) bool { return r.YearBuilt > 0 }},
+	{"photos", func(r contracts.RealEstate) bool { return len(r.Photos) > 0 }},
+	{"location", func(r contracts.RealEstate) bool { return r.Latitude != 0 || r.Longitude != 0 }},
+}
+
+// strategyRecord is the report of a strategy along with the fields of the
+// listings it saved.
+type strategyRecord struct {
+	report contracts.StrategyReport
+	filled map[string]int
+}
+
+// Recorder builds the report of a crawl session from what its strategies and
+// its repository did. Its methods are safe for concurrent use.
+type Recorder struct {
+	mutex      sync.Mutex
+	session    *Session
+	strategies []*strategyRecord
+	// pages maps the detail pages visited to the strategy that found them,
+	// and listingPages the listing pages to the strategy that visited them.
+	pages        map[string]*strategyRecord
+	listingPages map[string]*strategyRecord
+	agencies     map[string]bool
+}
+
+// NewRecorder creates the recorder of the session.
+func NewRecorder(session *Session) *Recorder {
+	return &Recorder{
+		session:      session,
+		pages:        make(map[string]*strategyRecord),
+		listingPages: make(map[string]*strategyRecord),
+		agencies:     make(map[string]bool),
+	}
+}
+
+// Strategies wraps the scraper of every strategy of the source, so the pages
+// it visits are recorded.
+func (r *Recorder) Strategies(source string, strategies []scrapify.ScraperStrategy[contracts.RealEstate]) []scrapify.ScraperStrategy[contracts.RealEstate] {
+	wrapped := make([]scrapify.ScraperStrategy[contracts.RealEstate], 0, len(strategies))
+
+	for _, strategy := range strategies {
+		record := r.strategy(source, strategy.Url)
+
+		wrapped = append(wrapped, scrapify.ScraperStrategy[contracts.RealEstate]{
+			Scraper: &recordingScraper{recorder: r, record: record, scraper: strategy.Scraper},
+			Url:     strategy.Url,
+		})
+	}
+
+	return wrapped
+}
+
+// strategy returns the record of the seed of the source, creating it if needed.
+func (r *Recorder) strategy(source, seed string) *strategyRecord {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, record := range r.strategies {
+		if record.report.Source == source && record.report.Seed == seed {
+			return record
+		}
+	}
+
+	record := &strategyRecord{
+		report: contracts.StrategyReport{Source: source, Seed: seed},
+		filled: make(map[string]int),
+	}
+	r.strategies = append(r.strategies, record)
+
+	return record
+}
+
+// Visit returns the seed of the strategy of the source that visited the page,
+// and whether the page was one of its listing pages rather than a detail page.
+func (r *Recorder) Visit(source, url string) (seed string, listing bool, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if record, found := r.listingPages[url]; found && record.report.Source == source {
+		return record.report.Seed, true, true
+	}
+	if record, found := r.pages[url]; found && record.report.Source == source {
+		return record.report.Seed, false, true
+	}
+
+	return "", false, false
+}
+
+// Source returns the source whose strategy visited the detail page, or an
+// empty string when none did.
+func (r *Recorder) Source(url string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if record, ok := r.pages[url]; ok {
+		return record.report.Source
+	}
+	return ""
+}
+
+// Observe records the outcome of the save of a listing.
+func (r *Recorder) Observe(item contracts.RealEstate, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, ok := r.pages[item.Url]
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		record.report.Failed++
+		return
+	}
+
+	record.report.Saved++
+	for _, field := range coverageFields {
+		if field.filled(item) {
+			record.filled[field.name]++
+		}
+	}
+
+	if item.Agency != "" {
+		r.agencies[item.Agency] = true
+	}
+}
+
+// Held records a listing held back for being invalid, by the policy of its
+// source.
+func (r *Recorder) Held(item contracts.RealEstate, policy contracts.InvalidPolicy) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, ok := r.pages[item.Url]
+	if !ok {
+		return
+	}
+
+	switch policy {
+	case contracts.QuarantineInvalid:
+		record.report.Quarantined++
+	case contracts.DropInvalid:
+		record.report.Dropped++
+	}
+}
+
+// Touched records the listings whose page did not change.
+func (r *Recorder) Touched(items []contracts.RealEstate) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, item := range items {
+		if record, ok := r.pages[item.Url]; ok {
+			record.report.Unchanged++
+		}
+
+		if item.Agency != "" {
+			r.agencies[item.Agency] = true
+		}
+	}
+}
+
+// Delisted records the listings of the scope delisted after the crawl of the
+// seed of the source.
+func (r *Recorder) Delisted(source, seed string, scope contracts.Hints, count int64) {
+	record := r.strategy(source, seed)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record.report.Delisted += int(count)
+
+	if count > 0 && scope.Agency != "" {
+		r.agencies[scope.Agency] = true
+	}
+}
+
+// Changed records the listings the crawl created and the ones whose price it
+// changed, as told by the repository.
+func (r *Recorder) Changed(changes contracts.CrawlChanges) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, url := range changes.New {
+		if record, ok := r.pages[url]; ok {
+			record.report.New++
+		}
+	}
+
+	for _, url := range changes.Repriced {
+		if record, ok := r.pages[url]; ok {
+			record.report.PriceChanges++
+		}
+	}
+}
+
+// Report returns the report of the session, finished now with the error of
+// the run, if any.
+func (r *Recorder) Report(err error) contracts.CrawlReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report := contracts.CrawlReport{
+		CrawlID:    r.session.ID,
+		StartedAt:  r.session.StartedAt,
+		FinishedAt: time.Now().UTC(),
+		Agencies:   make([]string, 0, len(r.agencies)),
+		Strategies: make([]contracts.StrategyReport, 0, len(r.strategies)),
+	}
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	for agency := range r.agencies {
+		report.Agencies = append(report.Agencies, agency)
+	}
+	slices.Sort(report.Agencies)
+
+	filled := make(map[string]int)
+	total := &report.Total
+
+	for _, record := range r.strategies {
+		strategy := record.report
+		strategy.Coverage = coverage(record.filled, strategy.Saved)
+		report.Strategies = append(report.Strategies, strategy)
+
+		total.ListingPages += strategy.ListingPages
+		total.DetailPages += strategy.DetailPages
+		total.Saved += strategy.Saved
+		total.Failed += strategy.Failed
+		total.Unchanged += strategy.Unchanged
+		total.New += strategy.New
+		total.PriceChanges += strategy.PriceChanges
+		total.Delisted += strategy.Delisted
+		total.Quarantined += strategy.Quarantined
+		total.Dropped += strategy.Dropped
+
+		for field, count := range record.filled {
+			filled[field] += count
+		}
+	}
+
+	total.Coverage = coverage(filled, total.Saved)
+
+	return report
+}
+
+// coverage turns the counts of listings with each field into percentages of
+// the saved ones, rounded to a decimal.
+func coverage(filled map[string]int, saved int) map[string]float64 {
+	percentages := make(map[string]float64, len(coverageFields))
+
+	for _, field := range coverageFields {
+		if saved == 0 {
+			percentages[field.name] = 0
+			continue
+		}
+
+		percentages[field.name] = math.Round(float64(filled[field.name])*1000/float64(saved)) / 10
+	}
+
+	return percentages
+}
+
+// WriteReport writes the report as indented JSON to a file of the directory
+// named after the crawl, creating the directory if needed, and returns the
+// path of the file. A crawl that is resumed overwrites its report.
+func WriteReport(dir string, report contracts.CrawlReport) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	path := filepath.Join(dir, report.CrawlID+".json")
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return path, nil
+}
+
+type recordingScraper struct {
+	recorder *Recorder
+	record   *strategyRecord
+	scraper  scrapify.IScraper[contracts.RealEstate]
+}
+
+func (s *recordingScraper) GetUrls(ctx context.Context, url string) ([]string, []string) {
+	urls, nextPages := s.scraper.GetUrls(ctx, url)
+
+	s.recorder.mutex.Lock()
+	s.record.report.ListingPages++
+	s.recorder.listingPages[url] = s.record
+	s.recorder.mutex.Unlock()
+
+	return urls, nextPages
+}
+
+func (s *recordingScraper) GetData(ctx context.Context, ch chan<- contracts.RealEstate, data *contracts.RealEstate, url string) {
+	s.recorder.mutex.Lock()
+	s.record.report.DetailPages++
+	s.recorder.pages[url] = s.record
+	s.recorder.mutex.Unlock()
+
+	s.scraper.GetData(ctx, ch, data, url)
+}
@@ -0,0 +1,181 @@
+package crawl
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Job runs a scheduled crawl and returns its crawl id.
+type Job func(ctx context.Context) (string, error)
+
+// RunOutcome is how a scheduled run ended.
+type RunOutcome struct {
+	CrawlID    string    `json:"crawlId,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ScheduleStatus reports the runs of a scheduled job.
+type ScheduleStatus struct {
+	Name     string      `json:"name"`
+	Schedule string      `json:"schedule"`
+	Running  bool        `json:"running"`
+	NextRun  time.Time   `json:"nextRun"`
+	LastRun  *RunOutcome `json:"lastRun,omitempty"`
+	// Overlaps counts the runs skipped because the previous one was not over.
+	Overlaps int `json:"overlaps"`
+}
+
+type scheduledJob struct {
+	name     string
+	schedule string
+	jitter   time.Duration
+	job      Job
+	id       cron.EntryID
+
+	running  bool
+	lastRun  *RunOutcome
+	overlaps int
+}
+
+// Scheduler runs jobs on cron expressions, never two runs of the same job at
+// once, each run starting up to the jitter of its job past its time.
+type Scheduler struct {
+	logger *slog.Logger
+	cron   *cron.Cron
+
+	mutex sync.Mutex
+	jobs  []*scheduledJob
+	ctx   context.Context
+}
+
+// NewScheduler creates a scheduler with no jobs.
+func NewScheduler(logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+		cron:   cron.New(),
+		ctx:    context.Background(),
+	}
+}
+
+// Add schedules a job on a standard cron expression, or a descriptor such as
+// @hourly or @every 6h.
+func (s *Scheduler) Add(name, schedule string, jitter time.Duration, job Job) error {
+	j := &scheduledJob{name: name, schedule: schedule, jitter: jitter, job: job}
+
+	id, err := s.cron.AddFunc(schedule, func() { s.run(j) })
+	if err != nil {
+		return err
+	}
+	j.id = id
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobs = append(s.jobs, j)
+
+	return nil
+}
+
+// Run starts the jobs on their schedules, with the given context, until it is
+// done, and then waits for the runs in flight to be over.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mutex.Lock()
+	s.ctx = ctx
+	s.mutex.Unlock()
+
+	s.cron.Start()
+
+	<-ctx.Done()
+
+	<-s.cron.Stop().Done()
+}
+
+// Status returns the status of every job, in the order they were added.
+func (s *Scheduler) Status() []ScheduleStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]ScheduleStatus, 0, len(s.jobs))
+
+	for _, j := range s.jobs {
+		status := ScheduleStatus{
+			Name:     j.name,
+			Schedule: j.schedule,
+			Running:  j.running,
+			NextRun:  s.cron.Entry(j.id).Next,
+			Overlaps: j.overlaps,
+		}
+
+		if j.lastRun != nil {
+			last := *j.lastRun
+			status.LastRun = &last
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func (s *Scheduler) run(j *scheduledJob) {
+	s.mutex.Lock()
+	ctx := s.ctx
+
+	if j.running {
+		j.overlaps++
+		s.mutex.Unlock()
+
+		s.logger.Warn("Skipping scheduled run, the previous one is not over", "job", j.name)
+		return
+	}
+
+	j.running = true
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		j.running = false
+		s.mutex.Unlock()
+	}()
+
+	if j.jitter > 0 {
+		timer := time.NewTimer(rand.N(j.jitter))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	outcome := RunOutcome{StartedAt: time.Now().UTC()}
+	s.logger.Info("Starting scheduled run", "job", j.name)
+
+	crawlID, err := j.job(ctx)
+
+	outcome.CrawlID = crawlID
+	outcome.FinishedAt = time.Now().UTC()
+
+	if err != nil {
+		outcome.Error = err.Error()
+		s.logger.Error("Scheduled run failed", "job", j.name, "crawlId", crawlID, "error", err)
+	} else {
+		s.logger.Info("Scheduled run completed", "job", j.name, "crawlId", crawlID)
+	}
+
+	s.mutex.Lock()
+	j.lastRun = &outcome
+	s.mutex.Unlock()
+}
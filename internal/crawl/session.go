@@ -0,0 +1,118 @@
+package crawl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"baia/internal/contracts"
+)
+
+// Session identifies one run of the scraper and keeps track of what it saw,
+// so listings that were not seen can be told apart from the ones that were.
+type Session struct {
+	ID        string
+	StartedAt time.Time
+
+	mutex  sync.Mutex
+	saved  []contracts.Hints
+	failed []contracts.Hints
+	// sourceFailures counts by source the listings that failed to be saved.
+	sourceFailures map[string]int
+}
+
+// NewSession starts a crawl session with a new, time sortable id.
+func NewSession() *Session {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	now := time.Now().UTC()
+
+	return &Session{
+		ID:        now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		StartedAt: now,
+	}
+}
+
+// ResumeSession continues the crawl session with the given id, so listings
+// saved before and after the resume belong to the same crawl.
+func ResumeSession(id string) *Session {
+	return &Session{ID: id, StartedAt: time.Now().UTC()}
+}
+
+// Restore records the scopes of listings saved by an earlier attempt of the
+// session, which a resumed crawl does not scrape again.
+func (s *Session) Restore(saved []contracts.Hints) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.saved = append(s.saved, saved...)
+}
+
+// Observe records the outcome of saving a listing of the source during the
+// session.
+func (s *Session) Observe(source string, r contracts.RealEstate, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scope := r.Scope()
+
+	if err != nil {
+		s.failed = append(s.failed, scope)
+
+		if s.sourceFailures == nil {
+			s.sourceFailures = make(map[string]int)
+		}
+		s.sourceFailures[source]++
+	} else {
+		s.saved = append(s.saved, scope)
+	}
+}
+
+// Complete reports whether the listings of the scope were crawled well enough
+// to trust that the ones not seen are gone: at least one listing was saved
+// and none of them failed to be saved.
+func (s *Session) Complete(scope contracts.Hints) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, failed := range s.failed {
+		if scope.Covers(failed) {
+			return false
+		}
+	}
+
+	for _, saved := range s.saved {
+		if scope.Covers(saved) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Failures returns how many listings failed to be saved during the session.
+func (s *Session) Failures() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.failed)
+}
+
+// SourceFailures returns how many listings of the source failed to be saved
+// during the session.
+func (s *Session) SourceFailures(source string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.sourceFailures[source]
+}
+
+// Saved returns how many listings were saved during the session.
+func (s *Session) Saved() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.saved)
+}
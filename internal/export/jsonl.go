@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"baia/internal/contracts"
+)
+
+// JSONLinesWriter writes real estates as JSON Lines, one object per line, with
+// the field names of the json tags of contracts.RealEstate.
+type JSONLinesWriter struct {
+	encoder *json.Encoder
+	mutex   sync.Mutex // Keeps lines written from several scrapers whole
+}
+
+// NewJSONLinesWriter is a constructor that creates a new JSONLinesWriter on w.
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &JSONLinesWriter{encoder: encoder}
+}
+
+// Write appends the real estate as a new line.
+func (j *JSONLinesWriter) Write(r contracts.RealEstate) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if err := j.encoder.Encode(r); err != nil {
+		return fmt.Errorf("failed to write real estate %s: %w", r.Url, err)
+	}
+
+	return nil
+}
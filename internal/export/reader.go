@@ -0,0 +1,214 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"baia/internal/contracts"
+)
+
+// ListSeparator separates the photos and tags of a real estate in a CSV cell.
+const ListSeparator = "|"
+
+// ReadFile reads the real estates of a dump, as CSV when the file has the .csv
+// extension and as JSON Lines otherwise.
+func ReadFile(path string) ([]contracts.RealEstate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dump: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return ReadCSV(file)
+	}
+
+	return ReadJSONLines(file)
+}
+
+// ReadJSONLines reads real estates written by JSONLinesWriter, skipping blank lines.
+func ReadJSONLines(r io.Reader) ([]contracts.RealEstate, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var list []contracts.RealEstate
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var realEstate contracts.RealEstate
+		if err := json.Unmarshal([]byte(text), &realEstate); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		list = append(list, realEstate)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dump: %w", err)
+	}
+
+	return list, nil
+}
+
+// csvColumns parses the cells of a CSV dump, by the header of their column.
+// Headers are the json names of the fields of contracts.RealEstate.
+var csvColumns = map[string]func(r *contracts.RealEstate, cell string) error{
+	"id":             func(r *contracts.RealEstate, cell string) error { r.ID = cell; return nil },
+	"code":           func(r *contracts.RealEstate, cell string) error { r.Code = cell; return nil },
+	"type":           func(r *contracts.RealEstate, cell string) error { r.Type = cell; return nil },
+	"name":           func(r *contracts.RealEstate, cell string) error { r.Name = cell; return nil },
+	"normalizedName": func(r *contracts.RealEstate, cell string) error { r.NormalizedName = cell; return nil },
+	"description":    func(r *contracts.RealEstate, cell string) error { r.Description = cell; return nil },
+	"url":            func(r *contracts.RealEstate, cell string) error { r.Url = cell; return nil },
+	"price":          intColumn(func(r *contracts.RealEstate) *int { return &r.Price }),
+	"priceOnRequest": boolColumn(func(r *contracts.RealEstate) *bool { return &r.PriceOnRequest }),
+	"bedrooms":       intColumn(func(r *contracts.RealEstate) *int { return &r.Bedrooms }),
+	"bathrooms":      intColumn(func(r *contracts.RealEstate) *int { return &r.Bathrooms }),
+	"area":           intColumn(func(r *contracts.RealEstate) *int { return &r.Area }),
+	"garageSpaces":   intColumn(func(r *contracts.RealEstate) *int { return &r.GarageSpaces }),
+	"city":           func(r *contracts.RealEstate, cell string) error { r.City = cell; return nil },
+	"district":       func(r *contracts.RealEstate, cell string) error { r.District = cell; return nil },
+	"latitude":       floatColumn(func(r *contracts.RealEstate) *float64 { return &r.Latitude }),
+	"longitude":      floatColumn(func(r *contracts.RealEstate) *float64 { return &r.Longitude }),
+	"furnished":      boolColumn(func(r *contracts.RealEstate) *bool { return &r.Furnished }),
+	"yearBuilt":      intColumn(func(r *contracts.RealEstate) *int { return &r.YearBuilt }),
+	"photos":         listColumn(func(r *contracts.RealEstate) *[]string { return &r.Photos }),
+	"tags":           listColumn(func(r *contracts.RealEstate) *[]string { return &r.Tags }),
+	"agency":         func(r *contracts.RealEstate, cell string) error { r.Agency = cell; return nil },
+	"forSale":        boolColumn(func(r *contracts.RealEstate) *bool { return &r.ForSale }),
+	"forRent":        boolColumn(func(r *contracts.RealEstate) *bool { return &r.ForRent }),
+	"delisted":       boolColumn(func(r *contracts.RealEstate) *bool { return &r.Delisted }),
+	"crawlId":        func(r *contracts.RealEstate, cell string) error { r.CrawlID = cell; return nil },
+	"observedAt": func(r *contracts.RealEstate, cell string) error {
+		if cell == "" {
+			return nil
+		}
+
+		t, err := time.Parse(time.RFC3339, cell)
+		if err != nil {
+			return err
+		}
+
+		r.ObservedAt = t
+		return nil
+	},
+}
+
+func intColumn(field func(r *contracts.RealEstate) *int) func(r *contracts.RealEstate, cell string) error {
+	return func(r *contracts.RealEstate, cell string) error {
+		if cell == "" {
+			return nil
+		}
+
+		n, err := strconv.Atoi(cell)
+		if err != nil {
+			return err
+		}
+
+		*field(r) = n
+		return nil
+	}
+}
+
+func floatColumn(field func(r *contracts.RealEstate) *float64) func(r *contracts.RealEstate, cell string) error {
+	return func(r *contracts.RealEstate, cell string) error {
+		if cell == "" {
+			return nil
+		}
+
+		n, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return err
+		}
+
+		*field(r) = n
+		return nil
+	}
+}
+
+func boolColumn(field func(r *contracts.RealEstate) *bool) func(r *contracts.RealEstate, cell string) error {
+	return func(r *contracts.RealEstate, cell string) error {
+		if cell == "" {
+			return nil
+		}
+
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return err
+		}
+
+		*field(r) = b
+		return nil
+	}
+}
+
+func listColumn(field func(r *contracts.RealEstate) *[]string) func(r *contracts.RealEstate, cell string) error {
+	return func(r *contracts.RealEstate, cell string) error {
+		for _, item := range strings.Split(cell, ListSeparator) {
+			if item = strings.TrimSpace(item); item != "" {
+				*field(r) = append(*field(r), item)
+			}
+		}
+		return nil
+	}
+}
+
+// ReadCSV reads real estates from a CSV dump whose first line names the column
+// of each field. Missing columns leave their fields empty, photos and tags are
+// separated by ListSeparator and observedAt is in RFC 3339.
+func ReadCSV(r io.Reader) ([]contracts.RealEstate, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var errs []error
+	for _, column := range header {
+		if _, ok := csvColumns[column]; !ok {
+			errs = append(errs, fmt.Errorf("unknown column %q", column))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	var list []contracts.RealEstate
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		var realEstate contracts.RealEstate
+		for i, cell := range record {
+			if err := csvColumns[header[i]](&realEstate, strings.TrimSpace(cell)); err != nil {
+				return nil, fmt.Errorf("line %d, column %q: %w", line, header[i], err)
+			}
+		}
+
+		list = append(list, realEstate)
+	}
+
+	return list, nil
+}
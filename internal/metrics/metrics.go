@@ -0,0 +1,188 @@
+// Package metrics keeps the counters and histograms of the health of the
+// crawls and of the writes to the graph, and serves them to Prometheus.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"baia/internal/contracts"
+	"baia/pkg/collector"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are the metrics of one baia process. Its methods are safe for
+// concurrent use.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	pages         *prometheus.CounterVec
+	responses     *prometheus.CounterVec
+	fetchFailures *prometheus.CounterVec
+	listings      *prometheus.CounterVec
+	parseErrors   *prometheus.CounterVec
+	violations    *prometheus.CounterVec
+	writes        *prometheus.HistogramVec
+	writeFailures prometheus.Counter
+	runs          *prometheus.HistogramVec
+	lastSuccess   *prometheus.GaugeVec
+}
+
+// New creates the metrics, along with the ones of the Go runtime and of the
+// process.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		pages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baia_pages_visited_total",
+			Help: "Pages fetched by the collectors of a source.",
+		}, []string{"source"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baia_http_responses_total",
+			Help: "HTTP responses by host and status code, retries included.",
+		}, []string{"host", "code"}),
+		fetchFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baia_fetch_failures_total",
+			Help: "Pages of a source that could not be fetched after every attempt, by kind of failure.",
+		}, []string{"source", "kind"}),
+		listings: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baia_listings_parsed_total",
+			Help: "Listings extracted from the detail pages of a source.",
+		}, []string{"source"}),
+		parseErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baia_field_parse_errors_total",
+			Help: "Texts the setter of a field of a source failed to parse.",
+		}, []string{"source", "field"}),
+		violations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "baia_validation_violations_total",
+			Help: "Validation rules broken by the listings of a source, by field and severity.",
+		}, []string{"source", "field", "severity"}),
+		writes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "baia_neo4j_write_duration_seconds",
+			Help:    "Time taken to write a batch of real estates to Neo4j, retries included.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		}, []string{"result"}),
+		writeFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "baia_neo4j_write_failures_total",
+			Help: "Real estates that failed to be written to Neo4j.",
+		}),
+		runs: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "baia_crawl_duration_seconds",
+			Help:    "Time taken to crawl a source.",
+			Buckets: prometheus.ExponentialBuckets(30, 2, 10),
+		}, []string{"source"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "baia_last_success_timestamp_seconds",
+			Help: "Unix time the last successful crawl of a source finished.",
+		}, []string{"source"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.pages,
+		m.responses,
+		m.fetchFailures,
+		m.listings,
+		m.parseErrors,
+		m.violations,
+		m.writes,
+		m.writeFailures,
+		m.runs,
+		m.lastSuccess,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Collector returns the collector option counting the pages of the source and
+// the status codes of every response.
+func (m *Metrics) Collector(source string) collector.Option {
+	return func(c *colly.Collector) {
+		c.OnResponse(func(r *colly.Response) {
+			m.pages.WithLabelValues(source).Inc()
+			m.response(r)
+		})
+
+		// Responses with an error status only reach the error handlers.
+		c.OnError(func(r *colly.Response, err error) {
+			if r.StatusCode != 0 {
+				m.response(r)
+			}
+		})
+	}
+}
+
+func (m *Metrics) response(r *colly.Response) {
+	m.responses.WithLabelValues(r.Request.URL.Hostname(), strconv.Itoa(r.StatusCode)).Inc()
+}
+
+// Tracer returns the tracer counting the texts the setters of the source
+// failed to parse.
+func (m *Metrics) Tracer(source string) contracts.Tracer {
+	return &tracer{metrics: m, source: source}
+}
+
+type tracer struct {
+	metrics *Metrics
+	source  string
+}
+
+func (t *tracer) Field(name, selector string) {}
+
+// Step counts the texts that failed to parse. Fields that are only missing
+// from the page are not parse errors.
+func (t *tracer) Step(step contracts.TraceStep) {
+	if step.Err != nil && !errors.Is(step.Err, contracts.ErrNoValue) {
+		t.metrics.parseErrors.WithLabelValues(t.source, step.Field).Inc()
+	}
+}
+
+// FetchFailed counts pages of the source that could not be fetched.
+func (m *Metrics) FetchFailed(source string, kind collector.FailureKind, count int) {
+	m.fetchFailures.WithLabelValues(source, string(kind)).Add(float64(count))
+}
+
+// Parsed counts a listing extracted from a page of the source.
+func (m *Metrics) Parsed(source string) {
+	m.listings.WithLabelValues(source).Inc()
+}
+
+// Violated counts the validation rules broken by a listing of the source.
+func (m *Metrics) Violated(source string, violations contracts.Violations) {
+	for _, violation := range violations {
+		m.violations.WithLabelValues(source, violation.Field, violation.Severity.String()).Inc()
+	}
+}
+
+// Written observes the write of a batch of rows to Neo4j.
+func (m *Metrics) Written(rows int, took time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+		m.writeFailures.Add(float64(rows))
+	}
+
+	m.writes.WithLabelValues(result).Observe(took.Seconds())
+}
+
+// Crawled observes the crawl of the source started at the given time, which
+// is the last successful one when it succeeded.
+func (m *Metrics) Crawled(source string, started time.Time, succeeded bool) {
+	m.runs.WithLabelValues(source).Observe(time.Since(started).Seconds())
+
+	if succeeded {
+		m.lastSuccess.WithLabelValues(source).SetToCurrentTime()
+	}
+}
@@ -0,0 +1,159 @@
+// Package quarantine keeps the listings held back from the graph for being
+// invalid, with what they were extracted from, until a fixed scraper handles
+// them again.
+package quarantine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"baia/internal/contracts"
+)
+
+// ErrNotFound is returned for ids that match no quarantined listing.
+var ErrNotFound = errors.New("listing not in quarantine")
+
+// Item is a quarantined listing.
+type Item struct {
+	ID            string    `json:"id"`
+	Source        string    `json:"source"`
+	Url           string    `json:"url"`
+	CrawlID       string    `json:"crawlId"`
+	QuarantinedAt time.Time `json:"quarantinedAt"`
+	// Hints are the ones of the seed the listing was found from, which its
+	// scraper needs to handle it again.
+	Hints contracts.Hints `json:"hints"`
+	// Raw holds, by field, the texts the selectors matched on the page.
+	Raw        map[string][]string  `json:"raw"`
+	Violations contracts.Violations `json:"violations"`
+	RealEstate contracts.RealEstate `json:"realEstate"`
+}
+
+// ID returns the id of the listing of the source at the URL, which stays the
+// same every time the listing is quarantined.
+func ID(source, url string) string {
+	sum := sha256.Sum256([]byte(source + " " + url))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// Store keeps quarantined listings in a directory, one JSON file each.
+type Store struct {
+	dir string
+}
+
+// Open opens the store in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Put quarantines the listing, replacing the item of an earlier quarantine.
+// The id of the item is set from its source and URL.
+func (s *Store) Put(item Item) error {
+	item.ID = ID(item.Source, item.Url)
+
+	content, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode quarantined listing: %w", err)
+	}
+
+	// Readers never see a partial item.
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(item.ID))
+}
+
+// List returns the quarantined listings, the most recent first.
+func (s *Store) List() ([]Item, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(paths))
+	for _, path := range paths {
+		item, err := s.load(path)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	slices.SortFunc(items, func(a, b Item) int {
+		return b.QuarantinedAt.Compare(a.QuarantinedAt)
+	})
+
+	return items, nil
+}
+
+// Get returns the quarantined listing with the id, or with the only id that
+// starts with it.
+func (s *Store) Get(id string) (Item, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return Item{}, fmt.Errorf("%w: %q", ErrNotFound, id)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, id+"*.json"))
+	if err != nil {
+		return Item{}, err
+	}
+
+	switch len(paths) {
+	case 0:
+		return Item{}, fmt.Errorf("%w: %q", ErrNotFound, id)
+	case 1:
+		return s.load(paths[0])
+	default:
+		return Item{}, fmt.Errorf("id %q matches %d quarantined listings", id, len(paths))
+	}
+}
+
+// Remove releases the listing with the id from quarantine. Removing a listing
+// that is not quarantined does nothing.
+func (s *Store) Remove(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) load(path string) (Item, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Item{}, err
+	}
+
+	var item Item
+	if err := json.Unmarshal(content, &item); err != nil {
+		return Item{}, fmt.Errorf("invalid quarantined listing %s: %w", filepath.Base(path), err)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,411 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"baia/internal/contracts"
+	"baia/internal/utils"
+	"baia/pkg/page"
+)
+
+// memoryEntry is a real estate kept by MemoryRealEstateRepository.
+type memoryEntry struct {
+	realEstate contracts.RealEstate
+	prices     []contracts.PricePoint
+	// firstCrawlID and repricedCrawlID are the crawls that created the real
+	// estate and that last changed its price.
+	firstCrawlID    string
+	repricedCrawlID string
+	// lastSeenAt is when the real estate was last observed.
+	lastSeenAt time.Time
+}
+
+// MemoryRealEstateRepository keeps real estates in memory. It follows the
+// rules of the Neo4j repository, so it can stand in for it in tools and dry runs.
+type MemoryRealEstateRepository struct {
+	report  contracts.SaveReport
+	mutex   sync.Mutex
+	entries map[string]*memoryEntry
+	ids     map[string]string
+	runs    map[string]contracts.CrawlReport
+}
+
+// NewMemoryRealEstateRepository creates a new, empty MemoryRealEstateRepository.
+// report, when not nil, is called after every save.
+func NewMemoryRealEstateRepository(report contracts.SaveReport) *MemoryRealEstateRepository {
+	return &MemoryRealEstateRepository{
+		report:  report,
+		entries: make(map[string]*memoryEntry),
+		ids:     make(map[string]string),
+		runs:    make(map[string]contracts.CrawlReport),
+	}
+}
+
+// Save stores the real estate right away, replacing the one with the same key.
+func (m *MemoryRealEstateRepository) Save(ctx context.Context, r contracts.RealEstate) error {
+	err := m.save(r)
+
+	if m.report != nil {
+		m.report([]contracts.RealEstate{r}, err)
+	}
+
+	return err
+}
+
+func (m *MemoryRealEstateRepository) save(r contracts.RealEstate) error {
+	key, err := r.Key()
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoryEntry{firstCrawlID: r.CrawlID}
+		m.entries[key] = entry
+
+		r.ID = newID()
+		m.ids[r.ID] = key
+	} else {
+		r.ID = entry.realEstate.ID
+	}
+
+	observedAt := r.ObservedAt
+	if observedAt.IsZero() {
+		observedAt = time.Now().UTC()
+	}
+
+	// Like in the graph, an observation older than the last one only adds its
+	// price to the history.
+	if ok && observedAt.Before(entry.lastSeenAt) {
+		if r.Price > 0 {
+			entry.prices = insertPrice(entry.prices, r, observedAt)
+		}
+		return nil
+	}
+
+	entry.lastSeenAt = observedAt
+	r.Delisted = false
+
+	// Like in the graph, saves without validators keep the ones known.
+	if r.Page == (page.Validators{}) {
+		r.Page = entry.realEstate.Page
+	}
+
+	// Like in the graph, a price that was not found leaves the history as is.
+	prices := entry.prices
+	if r.Price <= 0 {
+		if len(prices) > 0 {
+			r.Price = prices[len(prices)-1].Value
+		}
+	} else if len(prices) == 0 || !samePrice(prices[len(prices)-1], r) {
+		if len(prices) > 0 {
+			entry.repricedCrawlID = r.CrawlID
+		}
+		entry.prices = append(prices, contracts.PricePoint{
+			Value:     r.Price,
+			ForSale:   r.ForSale,
+			ForRent:   r.ForRent,
+			CreatedAt: observedAt,
+		})
+	}
+
+	entry.realEstate = r
+
+	return nil
+}
+
+// insertPrice inserts the price of the real estate observed at the given time
+// between the prices observed before and after it, unless one of them already
+// has it. When the price after it has it, that price is dated back instead.
+func insertPrice(prices []contracts.PricePoint, r contracts.RealEstate, observedAt time.Time) []contracts.PricePoint {
+	i := sort.Search(len(prices), func(i int) bool {
+		return prices[i].CreatedAt.After(observedAt)
+	})
+
+	if i > 0 && samePrice(prices[i-1], r) {
+		return prices
+	}
+
+	if i < len(prices) && samePrice(prices[i], r) {
+		prices[i].CreatedAt = observedAt
+		return prices
+	}
+
+	return slices.Insert(prices, i, contracts.PricePoint{
+		Value:     r.Price,
+		ForSale:   r.ForSale,
+		ForRent:   r.ForRent,
+		CreatedAt: observedAt,
+	})
+}
+
+// samePrice reports whether the real estate is still offered at the price.
+func samePrice(p contracts.PricePoint, r contracts.RealEstate) bool {
+	return p.Value == r.Price && p.ForSale == r.ForSale && p.ForRent == r.ForRent
+}
+
+// Flush does nothing, as saves are not buffered.
+func (m *MemoryRealEstateRepository) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Get returns the real estate with the given id.
+func (m *MemoryRealEstateRepository) Get(ctx context.Context, id string) (contracts.RealEstate, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key, ok := m.ids[id]
+	if !ok {
+		return contracts.RealEstate{}, contracts.ErrNotFound
+	}
+
+	return m.entries[key].realEstate, nil
+}
+
+// Search returns the real estates matching the filter, ordered by key.
+func (m *MemoryRealEstateRepository) Search(ctx context.Context, filter contracts.SearchFilter) ([]contracts.RealEstate, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = contracts.DefaultSearchLimit
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]string, 0, len(m.entries))
+	for key, entry := range m.entries {
+		if matches(filter, entry.realEstate) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	list := make([]contracts.RealEstate, 0, filter.Limit)
+	for i := filter.Offset; i < len(keys) && len(list) < filter.Limit; i++ {
+		list = append(list, m.entries[keys[i]].realEstate)
+	}
+
+	return list, nil
+}
+
+// matches reports whether the real estate passes the filter.
+func matches(filter contracts.SearchFilter, r contracts.RealEstate) bool {
+	switch {
+	case r.Delisted && !filter.IncludeDelisted:
+		return false
+	case filter.Agency != "" && utils.NormalizeCityName(filter.Agency) != utils.NormalizeCityName(r.Agency):
+		return false
+	case filter.City != "" && utils.NormalizeCityName(filter.City) != utils.NormalizeCityName(r.City):
+		return false
+	case filter.Type != "" && filter.Type != r.Type:
+		return false
+	case filter.Transaction == contracts.Sale && !r.ForSale:
+		return false
+	case filter.Transaction == contracts.Rent && !r.ForRent:
+		return false
+	case filter.MinBedrooms > 0 && r.Bedrooms < filter.MinBedrooms:
+		return false
+	case filter.MinPrice > 0 && r.Price < filter.MinPrice:
+		return false
+	case filter.MaxPrice > 0 && r.Price > filter.MaxPrice:
+		return false
+	}
+
+	return true
+}
+
+// PriceHistory returns the prices of the real estate from the oldest to the newest.
+func (m *MemoryRealEstateRepository) PriceHistory(ctx context.Context, id string) ([]contracts.PricePoint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key, ok := m.ids[id]
+	if !ok {
+		return nil, contracts.ErrNotFound
+	}
+
+	prices := m.entries[key].prices
+
+	return append([]contracts.PricePoint(nil), prices...), nil
+}
+
+// Stats counts the real estates of every agency, ordered by agency.
+func (m *MemoryRealEstateRepository) Stats(ctx context.Context) ([]contracts.AgencyStats, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	byAgency := make(map[string]*contracts.AgencyStats)
+
+	for _, entry := range m.entries {
+		r := entry.realEstate
+		key := utils.NormalizeCityName(r.Agency)
+
+		stats, ok := byAgency[key]
+		if !ok {
+			stats = &contracts.AgencyStats{Agency: r.Agency}
+			byAgency[key] = stats
+		}
+
+		stats.Listings++
+		if r.ForSale {
+			stats.ForSale++
+		}
+		if r.ForRent {
+			stats.ForRent++
+		}
+		if r.Delisted {
+			stats.Delisted++
+		}
+	}
+
+	list := make([]contracts.AgencyStats, 0, len(byAgency))
+	for _, stats := range byAgency {
+		list = append(list, *stats)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Agency < list[j].Agency
+	})
+
+	return list, nil
+}
+
+// MarkDelisted flags the real estates of the scope that were not seen by the
+// given crawl, returning how many were flagged.
+func (m *MemoryRealEstateRepository) MarkDelisted(ctx context.Context, scope contracts.Hints, crawlID string) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var delisted int64
+
+	for _, entry := range m.entries {
+		r := &entry.realEstate
+
+		if r.Delisted || r.CrawlID == crawlID || !inScope(scope, *r) {
+			continue
+		}
+
+		r.Delisted = true
+		delisted++
+	}
+
+	return delisted, nil
+}
+
+// Validators returns, by URL, the validators of the detail pages the real
+// estates were last scraped from.
+func (m *MemoryRealEstateRepository) Validators(ctx context.Context) (map[string]page.Validators, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	validators := make(map[string]page.Validators)
+
+	for _, entry := range m.entries {
+		r := entry.realEstate
+		if r.Url != "" && r.Page != (page.Validators{}) {
+			validators[r.Url] = r.Page
+		}
+	}
+
+	return validators, nil
+}
+
+// Touch records that the crawl saw the real estates of the URLs unchanged,
+// without saving them again, and returns them.
+func (m *MemoryRealEstateRepository) Touch(ctx context.Context, urls []string, crawlID string) ([]contracts.RealEstate, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	seen := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		seen[url] = true
+	}
+
+	var touched []contracts.RealEstate
+
+	for _, entry := range m.entries {
+		r := &entry.realEstate
+
+		if !seen[r.Url] {
+			continue
+		}
+
+		r.CrawlID = crawlID
+		r.ObservedAt = time.Now().UTC()
+		r.Delisted = false
+		entry.lastSeenAt = r.ObservedAt
+
+		touched = append(touched, *r)
+	}
+
+	return touched, nil
+}
+
+// CrawlChanges returns the URLs of the real estates the crawl created and of
+// the ones whose price it changed.
+func (m *MemoryRealEstateRepository) CrawlChanges(ctx context.Context, crawlID string) (contracts.CrawlChanges, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var changes contracts.CrawlChanges
+
+	// Real estates saved outside of a crawl have no crawl id.
+	if crawlID == "" {
+		return changes, nil
+	}
+
+	for _, entry := range m.entries {
+		if entry.firstCrawlID == crawlID {
+			changes.New = append(changes.New, entry.realEstate.Url)
+		}
+		if entry.repricedCrawlID == crawlID {
+			changes.Repriced = append(changes.Repriced, entry.realEstate.Url)
+		}
+	}
+
+	return changes, nil
+}
+
+// SaveCrawlRun keeps the report of a crawl, replacing any earlier report of
+// the same crawl.
+func (m *MemoryRealEstateRepository) SaveCrawlRun(ctx context.Context, report contracts.CrawlReport) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.runs[report.CrawlID] = report
+
+	return nil
+}
+
+// inScope reports whether the real estate belongs to the scope, by the same
+// rules the Neo4j repository uses to delist.
+func inScope(scope contracts.Hints, r contracts.RealEstate) bool {
+	return matches(contracts.SearchFilter{
+		Agency:          scope.Agency,
+		Type:            scope.Type,
+		Transaction:     scope.Transaction,
+		IncludeDelisted: true,
+	}, r)
+}
+
+// newID returns a random id in the format of the ids generated by Neo4j.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	s := hex.EncodeToString(b)
+
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"baia/internal/contracts"
+)
+
+func TestInsertPrice(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC)
+	}
+	sale := func(value int, d int) contracts.PricePoint {
+		return contracts.PricePoint{Value: value, ForSale: true, CreatedAt: day(d)}
+	}
+	history := func() []contracts.PricePoint {
+		return []contracts.PricePoint{sale(100, 10), sale(120, 20)}
+	}
+
+	tests := []struct {
+		name       string
+		prices     []contracts.PricePoint
+		price      int
+		forRent    bool
+		observedAt time.Time
+		want       []contracts.PricePoint
+	}{
+		{"into an empty history", nil, 100, false, day(5), []contracts.PricePoint{sale(100, 5)}},
+		{"before every price", history(), 90, false, day(5), []contracts.PricePoint{sale(90, 5), sale(100, 10), sale(120, 20)}},
+		{"between two prices", history(), 110, false, day(15), []contracts.PricePoint{sale(100, 10), sale(110, 15), sale(120, 20)}},
+		{"same as the price before it", history(), 100, false, day(15), history()},
+		{"same as the price after it", history(), 120, false, day(15), []contracts.PricePoint{sale(100, 10), sale(120, 15)}},
+		{"same as the first price, observed earlier", history(), 100, false, day(5), []contracts.PricePoint{sale(100, 5), sale(120, 20)}},
+		{
+			"same value for rent",
+			history(), 100, true, day(15),
+			[]contracts.PricePoint{sale(100, 10), {Value: 100, ForRent: true, CreatedAt: day(15)}, sale(120, 20)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := contracts.RealEstate{Price: test.price, ForSale: !test.forRent, ForRent: test.forRent}
+
+			got := insertPrice(test.prices, r, test.observedAt)
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("insertPrice() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// seed returns a repository with the real estates saved, by URL.
+func seed(t *testing.T, estates map[string]contracts.RealEstate) *MemoryRealEstateRepository {
+	t.Helper()
+
+	repo := NewMemoryRealEstateRepository(nil)
+	for url, r := range estates {
+		r.Url = url
+		if err := repo.Save(context.Background(), r); err != nil {
+			t.Fatalf("Save(%s): %v", url, err)
+		}
+	}
+
+	return repo
+}
+
+// delisted returns the URLs of the delisted real estates of the repository.
+func delisted(repo *MemoryRealEstateRepository) []string {
+	var urls []string
+	for _, entry := range repo.entries {
+		if entry.realEstate.Delisted {
+			urls = append(urls, entry.realEstate.Url)
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+func TestMarkDelisted(t *testing.T) {
+	estates := func() map[string]contracts.RealEstate {
+		return map[string]contracts.RealEstate{
+			"https://a.com.br/1": {Agency: "Agência A", Type: contracts.House, ForSale: true, CrawlID: "old"},
+			"https://a.com.br/2": {Agency: "Agência A", Type: contracts.Apartment, ForRent: true, CrawlID: "old"},
+			"https://a.com.br/3": {Agency: "Agência A", Type: contracts.House, ForSale: true, CrawlID: "new"},
+			"https://b.com.br/1": {Agency: "Agência B", Type: contracts.House, ForSale: true, CrawlID: "old"},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		scope contracts.Hints
+		want  []string
+	}{
+		{"agency", contracts.Hints{Agency: "agencia a"}, []string{"https://a.com.br/1", "https://a.com.br/2"}},
+		{"agency and transaction", contracts.Hints{Agency: "Agência A", Transaction: contracts.Rent}, []string{"https://a.com.br/2"}},
+		{"agency and type", contracts.Hints{Agency: "Agência A", Type: contracts.House}, []string{"https://a.com.br/1"}},
+		{"agency without listings", contracts.Hints{Agency: "Agência C"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := seed(t, estates())
+
+			count, err := repo.MarkDelisted(context.Background(), test.scope, "new")
+			if err != nil {
+				t.Fatalf("MarkDelisted() error = %v", err)
+			}
+
+			if got := delisted(repo); !slices.Equal(got, test.want) {
+				t.Errorf("delisted = %v, want %v", got, test.want)
+			}
+			if count != int64(len(test.want)) {
+				t.Errorf("MarkDelisted() = %d, want %d", count, len(test.want))
+			}
+
+			// Real estates already delisted are not counted again.
+			if count, _ := repo.MarkDelisted(context.Background(), test.scope, "new"); count != 0 {
+				t.Errorf("MarkDelisted() again = %d, want 0", count)
+			}
+		})
+	}
+}
+
+func TestTouch(t *testing.T) {
+	tests := []struct {
+		name string
+		urls []string
+		want []string
+	}{
+		{"no urls", nil, nil},
+		{"known urls", []string{"https://a.com.br/1", "https://a.com.br/2"}, []string{"https://a.com.br/1", "https://a.com.br/2"}},
+		{"unknown url", []string{"https://a.com.br/1", "https://a.com.br/9"}, []string{"https://a.com.br/1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := seed(t, map[string]contracts.RealEstate{
+				"https://a.com.br/1": {Agency: "Agência A", Code: "1", Price: 100_000, ForSale: true, CrawlID: "old"},
+				"https://a.com.br/2": {Agency: "Agência A", Code: "2", Price: 200_000, ForSale: true, CrawlID: "old"},
+			})
+			repo.MarkDelisted(context.Background(), contracts.Hints{Agency: "Agência A"}, "other")
+
+			touched, err := repo.Touch(context.Background(), test.urls, "new")
+			if err != nil {
+				t.Fatalf("Touch() error = %v", err)
+			}
+
+			var urls []string
+			for _, r := range touched {
+				urls = append(urls, r.Url)
+				if r.CrawlID != "new" || r.Delisted {
+					t.Errorf("touched %s has crawl %q and delisted %v, want crawl \"new\" and listed", r.Url, r.CrawlID, r.Delisted)
+				}
+			}
+			slices.Sort(urls)
+
+			if !slices.Equal(urls, test.want) {
+				t.Errorf("Touch() = %v, want %v", urls, test.want)
+			}
+
+			// Touching keeps the price history as is.
+			for _, r := range touched {
+				prices, _ := repo.PriceHistory(context.Background(), r.ID)
+				if len(prices) != 1 || prices[0].Value != r.Price {
+					t.Errorf("price history of %s = %v, want only %d", r.Url, prices, r.Price)
+				}
+			}
+
+			// Only the real estates that were not touched stay delisted.
+			var untouched []string
+			for _, url := range []string{"https://a.com.br/1", "https://a.com.br/2"} {
+				if !slices.Contains(test.want, url) {
+					untouched = append(untouched, url)
+				}
+			}
+			if got := delisted(repo); !slices.Equal(got, untouched) {
+				t.Errorf("delisted after Touch() = %v, want %v", got, untouched)
+			}
+		})
+	}
+}
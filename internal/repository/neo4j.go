@@ -0,0 +1,736 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"baia/internal/contracts"
+	"baia/internal/utils"
+	"baia/pkg/database"
+	"baia/pkg/page"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// saveQuery merges a batch of real estates, given as $rows built by row,
+// along with their price history, agency, city and district. Rows carry the
+// time the listing was observed, so replayed archives keep their dates: an
+// observation older than the last one seen leaves the properties and labels
+// as they are, and its price goes between the prices observed before and
+// after it, unless one of them already has it. A price of 0 is a price that
+// was not found, which leaves the history as is.
+var saveQuery = fmt.Sprintf(`
+	UNWIND $rows AS row
+	WITH row, coalesce(row.observedAt, datetime()) AS observedAt
+	MERGE (r:RealEstate {key: row.key})
+	ON CREATE SET
+			r.id = randomUUID(),
+			r.createdAt = observedAt,
+			r.firstCrawlId = row.crawlId
+	WITH r, row, observedAt, r.lastSeenAt IS NULL OR observedAt >= r.lastSeenAt AS current
+	SET
+			r.updatedAt = datetime(),
+			r.createdAt = CASE WHEN r.createdAt > observedAt THEN observedAt ELSE r.createdAt END
+	WITH r, row, observedAt, current
+	CALL {
+		WITH r, row, observedAt, current
+		WITH r, row, observedAt, current
+		WHERE current
+		SET
+				r += row.properties,
+				r.lastSeenAt = observedAt,
+				r.lastCrawlId = row.crawlId
+		%s
+		FOREACH (_ IN CASE WHEN row.forSale THEN [1] ELSE [] END | SET r:ForSale)
+		FOREACH (_ IN CASE WHEN row.forRent THEN [1] ELSE [] END | SET r:ForRent)
+		REMOVE r:Delisted, r.delistedAt
+	}
+	WITH r, row, observedAt, current
+	CALL {
+		WITH r, row, observedAt, current
+		WITH r, row, observedAt, current
+		WHERE row.price > 0
+		OPTIONAL MATCH (r)-[oldRel:LATEST_PRICE]->(oldPrice:Price)
+		WHERE (NOT row.forSale OR oldPrice:SalePrice) AND (NOT row.forRent OR oldPrice:RentalPrice)
+		WITH r, row, observedAt, current, oldRel, oldPrice
+		WHERE oldPrice IS NULL
+			OR (current AND oldPrice.value <> row.price AND coalesce(oldPrice.createdAt, observedAt) <= observedAt)
+		DELETE oldRel
+		CREATE (newPrice:Price {
+			id: randomUUID(),
+			value: row.price,
+			createdAt: observedAt,
+			crawlId: row.crawlId
+		})
+		FOREACH (_ IN CASE WHEN row.forSale THEN [1] ELSE [] END | SET newPrice:SalePrice)
+		FOREACH (_ IN CASE WHEN row.forRent THEN [1] ELSE [] END | SET newPrice:RentalPrice)
+		CREATE (r)-[:LATEST_PRICE]->(newPrice)
+		FOREACH (_ IN CASE WHEN oldPrice IS NOT NULL THEN [1] ELSE [] END |
+			CREATE (newPrice)<-[:NEXT]-(oldPrice)
+		)
+		WITH r, newPrice
+		OPTIONAL MATCH (r)-[:FIRST_PRICE]->(p:Price)
+		WITH r, newPrice, COUNT(p) AS existingFirst
+		WHERE existingFirst = 0
+		CREATE (r)-[:FIRST_PRICE]->(newPrice)
+	}
+	CALL {
+		WITH r, row, observedAt
+		WITH r, row, observedAt
+		WHERE row.price > 0
+		MATCH (r)-[:LATEST_PRICE]->(latest:Price)
+		WHERE (NOT row.forSale OR latest:SalePrice) AND (NOT row.forRent OR latest:RentalPrice)
+			AND latest.createdAt > observedAt
+		MATCH (p:Price)-[:NEXT*0..]->(latest)
+		WITH r, row, observedAt, latest, p
+		ORDER BY p.createdAt
+		WITH r, row, observedAt, latest, collect(p) AS chain
+		WITH r, row, observedAt,
+			[p IN chain WHERE p.createdAt <= observedAt][-1] AS previous,
+			[p IN chain WHERE p.createdAt > observedAt][0] AS next
+		WHERE previous IS NULL OR previous.value <> row.price
+		FOREACH (_ IN CASE WHEN next.value = row.price THEN [1] ELSE [] END |
+			SET next.createdAt = observedAt
+		)
+		WITH r, row, observedAt, previous, next
+		WHERE next.value <> row.price
+		OPTIONAL MATCH (previous)-[link:NEXT]->(next)
+		DELETE link
+		CREATE (newPrice:Price {
+			id: randomUUID(),
+			value: row.price,
+			createdAt: observedAt,
+			crawlId: row.crawlId
+		})
+		FOREACH (_ IN CASE WHEN row.forSale THEN [1] ELSE [] END | SET newPrice:SalePrice)
+		FOREACH (_ IN CASE WHEN row.forRent THEN [1] ELSE [] END | SET newPrice:RentalPrice)
+		CREATE (newPrice)-[:NEXT]->(next)
+		FOREACH (_ IN CASE WHEN previous IS NOT NULL THEN [1] ELSE [] END |
+			CREATE (previous)-[:NEXT]->(newPrice)
+		)
+		WITH r, observedAt, newPrice
+		OPTIONAL MATCH (r)-[first:FIRST_PRICE]->(f:Price)
+		WITH r, observedAt, newPrice, first, f
+		WHERE f IS NULL OR f.createdAt > observedAt
+		DELETE first
+		CREATE (r)-[:FIRST_PRICE]->(newPrice)
+	}
+	WITH r, row
+	MERGE (a:Agency {normalizedName: row.normalizedAgencyName})
+	ON CREATE SET
+			a.id = randomUUID(),
+			a.name = row.agency
+	MERGE (r)-[:SELLED_BY]->(a)
+	WITH r, row
+	MERGE (e:Estate {name: "Rio Grande do Sul", normalizedName: "riograndedosul"})
+	MERGE (c:City {normalizedName: row.normalizedCityName})
+	ON CREATE SET
+			c.id = randomUUID(),
+			c.name = row.city
+	MERGE (c)-[:IN]->(e)
+	MERGE (r)-[:IN]->(c)
+	WITH r, row, c
+	CALL {
+		WITH r, row, c
+		WITH r, row, c
+		WHERE row.district <> ""
+		MERGE (d:District {key: row.normalizedCityName + ":" + row.district})
+		ON CREATE SET
+				d.id = randomUUID(),
+				d.name = row.district
+		MERGE (d)-[:IN]->(c)
+		MERGE (r)-[:IN]->(d)
+	}
+	RETURN count(r) AS saved
+`, typeLabels())
+
+// typeLabels sets the label of the real estate type, since labels cannot be
+// parameters of a query.
+func typeLabels() string {
+	var sb strings.Builder
+
+	for _, t := range []string{contracts.House, contracts.Apartment, contracts.Land, contracts.Commercial, contracts.Industrial} {
+		fmt.Fprintf(&sb, "FOREACH (_ IN CASE WHEN row.type = %q THEN [1] ELSE [] END | SET r:%s)\n\t", t, t)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// row returns the real estate as a row of saveQuery.
+// Callers must check Key first, as rows without a key cannot be merged.
+func row(r contracts.RealEstate) map[string]any {
+	key, _ := r.Key()
+
+	properties := map[string]any{
+		"code":           r.Code,
+		"type":           r.Type,
+		"name":           r.Name,
+		"description":    r.Description,
+		"url":            r.Url,
+		"bedrooms":       r.Bedrooms,
+		"bathrooms":      r.Bathrooms,
+		"area":           r.Area,
+		"garageSpaces":   r.GarageSpaces,
+		"furnished":      r.Furnished,
+		"priceOnRequest": r.PriceOnRequest,
+		"yearBuilt":      r.YearBuilt,
+		"latitude":       r.Latitude,
+		"longitude":      r.Longitude,
+		"photos":         r.Photos,
+		"tags":           r.Tags,
+		"forSale":        r.ForSale,
+		"forRent":        r.ForRent,
+	}
+
+	// Real estates not scraped from a page, like replayed ones, keep the
+	// validators of the page they were last scraped from.
+	if r.Page.ContentHash != "" {
+		properties["pageEtag"] = r.Page.ETag
+		properties["pageLastModified"] = r.Page.LastModified
+		properties["pageHash"] = r.Page.ContentHash
+	}
+
+	return map[string]any{
+		"key":                  key,
+		"crawlId":              r.CrawlID,
+		"observedAt":           observedAt(r),
+		"type":                 r.Type,
+		"price":                r.Price,
+		"forSale":              r.ForSale,
+		"forRent":              r.ForRent,
+		"agency":               r.Agency,
+		"normalizedAgencyName": utils.NormalizeCityName(r.Agency),
+		"city":                 r.City,
+		"normalizedCityName":   utils.NormalizeCityName(r.City),
+		"district":             r.District,
+		"properties":           properties,
+	}
+}
+
+// observedAt returns when the real estate was observed, or nil to let the
+// query use the time of the write.
+func observedAt(r contracts.RealEstate) any {
+	if r.ObservedAt.IsZero() {
+		return nil
+	}
+
+	return r.ObservedAt
+}
+
+// Neo4jRealEstateRepository stores real estates in the Neo4j graph, writing
+// them in batches.
+type Neo4jRealEstateRepository struct {
+	driver neo4j.DriverWithContext
+	writer *database.BatchWriter[contracts.RealEstate]
+}
+
+// NewNeo4jRealEstateRepository creates a new instance of Neo4jRealEstateRepository.
+func NewNeo4jRealEstateRepository(driver neo4j.DriverWithContext, config database.BatchConfig, report contracts.SaveReport) *Neo4jRealEstateRepository {
+	return &Neo4jRealEstateRepository{
+		driver: driver,
+		writer: database.NewBatchWriter(driver, saveQuery, row, report, config),
+	}
+}
+
+// Save buffers the real estate, writing the batch when it is full. The outcome
+// of each real estate is delivered to the report of the repository.
+func (n *Neo4jRealEstateRepository) Save(ctx context.Context, r contracts.RealEstate) error {
+	if _, err := r.Key(); err != nil {
+		return err
+	}
+
+	return n.writer.Add(ctx, r)
+}
+
+// Flush writes the buffered real estates.
+func (n *Neo4jRealEstateRepository) Flush(ctx context.Context) error {
+	return n.writer.Flush(ctx)
+}
+
+// returnRealEstate projects a real estate node r with the names of its related
+// nodes, in the columns read by realEstateFromRecord.
+const returnRealEstate = `
+	RETURN
+		r,
+		r:Delisted AS delisted,
+		[(r)-[:SELLED_BY]->(a:Agency) | a.name][0] AS agency,
+		[(r)-[:IN]->(c:City) | c.name][0] AS city,
+		[(r)-[:IN]->(d:District) | d.name][0] AS district,
+		[(r)-[:LATEST_PRICE]->(p:Price) | p.value][0] AS price
+`
+
+// Get returns the real estate with the given id.
+func (n *Neo4jRealEstateRepository) Get(ctx context.Context, id string) (contracts.RealEstate, error) {
+	list, err := n.read(ctx, `MATCH (r:RealEstate {id: $id})`+returnRealEstate, map[string]any{"id": id})
+	if err != nil {
+		return contracts.RealEstate{}, err
+	}
+
+	if len(list) == 0 {
+		return contracts.RealEstate{}, contracts.ErrNotFound
+	}
+
+	return list[0], nil
+}
+
+// Search returns the real estates matching the filter, ordered by key.
+func (n *Neo4jRealEstateRepository) Search(ctx context.Context, filter contracts.SearchFilter) ([]contracts.RealEstate, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = contracts.DefaultSearchLimit
+	}
+
+	return n.read(ctx, `
+		MATCH (r:RealEstate)
+		WHERE ($includeDelisted OR NOT r:Delisted)
+			AND ($type = "" OR r.type = $type)
+			AND ($transaction <> "sale" OR r.forSale)
+			AND ($transaction <> "rent" OR r.forRent)
+			AND ($minBedrooms = 0 OR r.bedrooms >= $minBedrooms)
+			AND ($agency = "" OR EXISTS { (r)-[:SELLED_BY]->(:Agency {normalizedName: $agency}) })
+			AND ($city = "" OR EXISTS { (r)-[:IN]->(:City {normalizedName: $city}) })
+		WITH r, [(r)-[:LATEST_PRICE]->(p:Price) | p.value][0] AS latestPrice
+		WHERE ($minPrice = 0 OR latestPrice >= $minPrice)
+			AND ($maxPrice = 0 OR latestPrice <= $maxPrice)
+		WITH r
+		ORDER BY r.key
+		SKIP $offset
+		LIMIT $limit
+	`+returnRealEstate, map[string]any{
+		"includeDelisted": filter.IncludeDelisted,
+		"type":            filter.Type,
+		"transaction":     filter.Transaction,
+		"minBedrooms":     filter.MinBedrooms,
+		"agency":          utils.NormalizeCityName(filter.Agency),
+		"city":            utils.NormalizeCityName(filter.City),
+		"minPrice":        filter.MinPrice,
+		"maxPrice":        filter.MaxPrice,
+		"offset":          filter.Offset,
+		"limit":           filter.Limit,
+	})
+}
+
+// PriceHistory returns the prices of the real estate from the oldest to the newest.
+func (n *Neo4jRealEstateRepository) PriceHistory(ctx context.Context, id string) ([]contracts.PricePoint, error) {
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	history, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			MATCH (r:RealEstate {id: $id})-[:FIRST_PRICE]->(first:Price)
+			MATCH (first)-[:NEXT*0..]->(p:Price)
+			WITH DISTINCT p
+			RETURN p.value AS value, p:SalePrice AS forSale, p:RentalPrice AS forRent, p.createdAt AS createdAt
+			ORDER BY createdAt
+		`, map[string]any{"id": id})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read price history: %w", err)
+		}
+
+		history := make([]contracts.PricePoint, 0, len(records))
+		for _, record := range records {
+			value, _, _ := neo4j.GetRecordValue[int64](record, "value")
+			forSale, _, _ := neo4j.GetRecordValue[bool](record, "forSale")
+			forRent, _, _ := neo4j.GetRecordValue[bool](record, "forRent")
+			createdAt, _, _ := neo4j.GetRecordValue[time.Time](record, "createdAt")
+
+			history = append(history, contracts.PricePoint{
+				Value:     int(value),
+				ForSale:   forSale,
+				ForRent:   forRent,
+				CreatedAt: createdAt,
+			})
+		}
+
+		return history, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return history.([]contracts.PricePoint), nil
+}
+
+// Stats counts the real estates of every agency, ordered by agency.
+func (n *Neo4jRealEstateRepository) Stats(ctx context.Context) ([]contracts.AgencyStats, error) {
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	list, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			MATCH (r:RealEstate)-[:SELLED_BY]->(a:Agency)
+			RETURN
+				a.name AS agency,
+				count(r) AS listings,
+				count(CASE WHEN r.forSale THEN 1 END) AS forSale,
+				count(CASE WHEN r.forRent THEN 1 END) AS forRent,
+				count(CASE WHEN r:Delisted THEN 1 END) AS delisted
+			ORDER BY agency
+		`, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stats: %w", err)
+		}
+
+		list := make([]contracts.AgencyStats, 0, len(records))
+		for _, record := range records {
+			agency, _, _ := neo4j.GetRecordValue[string](record, "agency")
+			listings, _, _ := neo4j.GetRecordValue[int64](record, "listings")
+			forSale, _, _ := neo4j.GetRecordValue[int64](record, "forSale")
+			forRent, _, _ := neo4j.GetRecordValue[int64](record, "forRent")
+			delisted, _, _ := neo4j.GetRecordValue[int64](record, "delisted")
+
+			list = append(list, contracts.AgencyStats{
+				Agency:   agency,
+				Listings: int(listings),
+				ForSale:  int(forSale),
+				ForRent:  int(forRent),
+				Delisted: int(delisted),
+			})
+		}
+
+		return list, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.([]contracts.AgencyStats), nil
+}
+
+func (n *Neo4jRealEstateRepository) read(ctx context.Context, query string, params map[string]any) ([]contracts.RealEstate, error) {
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	list, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return collectRealEstates(ctx, tx, query, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.([]contracts.RealEstate), nil
+}
+
+// collectRealEstates runs a query ending with returnRealEstate.
+func collectRealEstates(ctx context.Context, tx neo4j.ManagedTransaction, query string, params map[string]any) ([]contracts.RealEstate, error) {
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	records, err := result.Collect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read real estates: %w", err)
+	}
+
+	list := make([]contracts.RealEstate, 0, len(records))
+	for _, record := range records {
+		r, err := realEstateFromRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+
+	return list, nil
+}
+
+// realEstateFromRecord reads the columns projected by returnRealEstate.
+func realEstateFromRecord(record *neo4j.Record) (contracts.RealEstate, error) {
+	node, _, err := neo4j.GetRecordValue[neo4j.Node](record, "r")
+	if err != nil {
+		return contracts.RealEstate{}, fmt.Errorf("failed to read real estate node: %w", err)
+	}
+
+	props := node.Props
+
+	r := contracts.RealEstate{
+		ID:             stringProp(props, "id"),
+		Code:           stringProp(props, "code"),
+		Type:           stringProp(props, "type"),
+		Name:           stringProp(props, "name"),
+		Description:    stringProp(props, "description"),
+		Url:            stringProp(props, "url"),
+		Bedrooms:       intProp(props, "bedrooms"),
+		Bathrooms:      intProp(props, "bathrooms"),
+		Area:           intProp(props, "area"),
+		GarageSpaces:   intProp(props, "garageSpaces"),
+		YearBuilt:      intProp(props, "yearBuilt"),
+		Latitude:       floatProp(props, "latitude"),
+		Longitude:      floatProp(props, "longitude"),
+		Furnished:      boolProp(props, "furnished"),
+		PriceOnRequest: boolProp(props, "priceOnRequest"),
+		ForSale:        boolProp(props, "forSale"),
+		ForRent:        boolProp(props, "forRent"),
+		Photos:         stringsProp(props, "photos"),
+		Tags:           stringsProp(props, "tags"),
+		CrawlID:        stringProp(props, "lastCrawlId"),
+		Page: page.Validators{
+			ETag:         stringProp(props, "pageEtag"),
+			LastModified: stringProp(props, "pageLastModified"),
+			ContentHash:  stringProp(props, "pageHash"),
+		},
+	}
+
+	r.Delisted, _, _ = neo4j.GetRecordValue[bool](record, "delisted")
+	r.Agency, _, _ = neo4j.GetRecordValue[string](record, "agency")
+	r.City, _, _ = neo4j.GetRecordValue[string](record, "city")
+	r.District, _, _ = neo4j.GetRecordValue[string](record, "district")
+
+	price, _, _ := neo4j.GetRecordValue[int64](record, "price")
+	r.Price = int(price)
+
+	return r, nil
+}
+
+func stringProp(props map[string]any, key string) string {
+	value, _ := props[key].(string)
+	return value
+}
+
+func intProp(props map[string]any, key string) int {
+	value, _ := props[key].(int64)
+	return int(value)
+}
+
+func floatProp(props map[string]any, key string) float64 {
+	value, _ := props[key].(float64)
+	return value
+}
+
+func boolProp(props map[string]any, key string) bool {
+	value, _ := props[key].(bool)
+	return value
+}
+
+func stringsProp(props map[string]any, key string) []string {
+	values, _ := props[key].([]any)
+
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
+
+// MarkDelisted labels as Delisted the listings of the scope that were not seen
+// by the given crawl, returning how many were marked. Listings seen again by a
+// later crawl lose the label when saved.
+func (n *Neo4jRealEstateRepository) MarkDelisted(ctx context.Context, scope contracts.Hints, crawlID string) (int64, error) {
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	count, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			MATCH (r:RealEstate)-[:SELLED_BY]->(:Agency {normalizedName: $normalizedAgencyName})
+			WHERE NOT r:Delisted
+				AND coalesce(r.lastCrawlId, "") <> $crawlId
+				AND ($type = "" OR r.type = $type)
+				AND ($transaction <> "sale" OR r.forSale)
+				AND ($transaction <> "rent" OR r.forRent)
+			SET r:Delisted, r.delistedAt = datetime()
+			RETURN count(r) AS delisted
+		`, map[string]any{
+			"normalizedAgencyName": utils.NormalizeCityName(scope.Agency),
+			"crawlId":              crawlID,
+			"type":                 scope.Type,
+			"transaction":          scope.Transaction,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		record, err := result.Single(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read result: %w", err)
+		}
+
+		delisted, _ := record.Get("delisted")
+		return delisted, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count.(int64), nil
+}
+
+// Validators returns, by URL, the validators of the detail pages the real
+// estates were last scraped from.
+func (n *Neo4jRealEstateRepository) Validators(ctx context.Context) (map[string]page.Validators, error) {
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	validators, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			MATCH (r:RealEstate)
+			WHERE r.pageHash IS NOT NULL AND r.url IS NOT NULL
+			RETURN r.url AS url, r.pageEtag AS etag, r.pageLastModified AS lastModified, r.pageHash AS hash
+		`, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read validators: %w", err)
+		}
+
+		validators := make(map[string]page.Validators, len(records))
+		for _, record := range records {
+			url, _, _ := neo4j.GetRecordValue[string](record, "url")
+			etag, _, _ := neo4j.GetRecordValue[string](record, "etag")
+			lastModified, _, _ := neo4j.GetRecordValue[string](record, "lastModified")
+			hash, _, _ := neo4j.GetRecordValue[string](record, "hash")
+
+			validators[url] = page.Validators{ETag: etag, LastModified: lastModified, ContentHash: hash}
+		}
+
+		return validators, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return validators.(map[string]page.Validators), nil
+}
+
+// Touch stamps the real estates of the URLs as seen by the crawl, without
+// saving them again, and returns them. Like saved ones, they lose the
+// Delisted label.
+func (n *Neo4jRealEstateRepository) Touch(ctx context.Context, urls []string, crawlID string) ([]contracts.RealEstate, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	list, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		return collectRealEstates(ctx, tx, `
+			UNWIND $urls AS url
+			MATCH (r:RealEstate {url: url})
+			SET r.lastSeenAt = datetime(), r.lastCrawlId = $crawlId
+			REMOVE r:Delisted, r.delistedAt
+			WITH r
+		`+returnRealEstate, map[string]any{"urls": urls, "crawlId": crawlID})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.([]contracts.RealEstate), nil
+}
+
+// CrawlChanges returns the URLs of the real estates the crawl created and of
+// the ones whose price it changed.
+func (n *Neo4jRealEstateRepository) CrawlChanges(ctx context.Context, crawlID string) (contracts.CrawlChanges, error) {
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	changes, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `
+			CALL {
+				MATCH (r:RealEstate {firstCrawlId: $crawlId})
+				RETURN collect(r.url) AS created
+			}
+			CALL {
+				MATCH (r:RealEstate)-[:LATEST_PRICE]->(:Price {crawlId: $crawlId})<-[:NEXT]-(:Price)
+				RETURN collect(DISTINCT r.url) AS repriced
+			}
+			RETURN created, repriced
+		`, map[string]any{"crawlId": crawlID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		record, err := result.Single(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read result: %w", err)
+		}
+
+		values := record.AsMap()
+
+		return contracts.CrawlChanges{
+			New:      stringsProp(values, "created"),
+			Repriced: stringsProp(values, "repriced"),
+		}, nil
+	})
+	if err != nil {
+		return contracts.CrawlChanges{}, err
+	}
+
+	return changes.(contracts.CrawlChanges), nil
+}
+
+// SaveCrawlRun stores the report as a CrawlRun node, with the totals of the
+// run as properties and the whole report as JSON, linked to the agencies the
+// run touched. A resumed crawl replaces the report of its earlier attempt.
+func (n *Neo4jRealEstateRepository) SaveCrawlRun(ctx context.Context, report contracts.CrawlReport) error {
+	content, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+
+	agencies := make([]string, 0, len(report.Agencies))
+	for _, agency := range report.Agencies {
+		agencies = append(agencies, utils.NormalizeCityName(agency))
+	}
+
+	session := n.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err = session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			MERGE (run:CrawlRun {id: $id})
+			SET run += $properties
+			WITH run
+			OPTIONAL MATCH (run)-[old:CRAWLED]->(:Agency)
+			DELETE old
+			WITH DISTINCT run
+			UNWIND $agencies AS agency
+			MATCH (a:Agency {normalizedName: agency})
+			MERGE (run)-[:CRAWLED]->(a)
+		`, map[string]any{
+			"id":       report.CrawlID,
+			"agencies": agencies,
+			"properties": map[string]any{
+				"startedAt":    report.StartedAt,
+				"finishedAt":   report.FinishedAt,
+				"error":        report.Error,
+				"listingPages": report.Total.ListingPages,
+				"detailPages":  report.Total.DetailPages,
+				"saved":        report.Total.Saved,
+				"failed":       report.Total.Failed,
+				"unchanged":    report.Total.Unchanged,
+				"new":          report.Total.New,
+				"priceChanges": report.Total.PriceChanges,
+				"delisted":     report.Total.Delisted,
+				"quarantined":  report.Total.Quarantined,
+				"dropped":      report.Total.Dropped,
+				"report":       string(content),
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+		return nil, nil
+	})
+
+	return err
+}
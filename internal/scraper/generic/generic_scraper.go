@@ -0,0 +1,266 @@
+package generic
+
+import (
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/scraper/structured"
+	"baia/pkg/collector"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/ricardocastanho/scrapify"
+)
+
+// setters maps the field names of a selectors block to the real estate setters.
+var setters = map[string]func(r *contracts.RealEstate, text string) error{
+	"code":         (*contracts.RealEstate).SetCode,
+	"name":         (*contracts.RealEstate).SetName,
+	"description":  (*contracts.RealEstate).SetDescription,
+	"price":        (*contracts.RealEstate).SetPrice,
+	"bedrooms":     (*contracts.RealEstate).SetBedrooms,
+	"bathrooms":    (*contracts.RealEstate).SetBathrooms,
+	"area":         (*contracts.RealEstate).SetArea,
+	"garageSpaces": (*contracts.RealEstate).SetGarageSpaces,
+	"district":     (*contracts.RealEstate).SetDistrict,
+	"city":         (*contracts.RealEstate).SetCity,
+	"yearBuilt":    (*contracts.RealEstate).SetYearBuilt,
+	"photos":       (*contracts.RealEstate).SetPhoto,
+	"tags":         (*contracts.RealEstate).SetTag,
+}
+
+// multiValued lists the fields that collect every match instead of the first one.
+var multiValued = []string{"photos", "tags"}
+
+// field is a compiled entry of the selectors block.
+type field struct {
+	name string
+	config.Field
+	regex *regexp.Regexp
+}
+
+// GenericSelectorScraper implements a real estate scraper whose selectors come
+// entirely from the sources file, for agency sites that only differ in markup.
+type GenericSelectorScraper struct {
+	logger    *slog.Logger
+	options   contracts.ScraperOptions
+	selectors config.Selectors
+	fields    []field
+}
+
+// NewGenericSelectorScraper creates a new instance of GenericSelectorScraper.
+func NewGenericSelectorScraper(logger *slog.Logger, selectors config.Selectors, options contracts.ScraperOptions) (scrapify.IScraper[contracts.RealEstate], error) {
+	if err := selectors.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := &GenericSelectorScraper{
+		logger:    logger,
+		options:   options,
+		selectors: selectors,
+	}
+
+	for _, name := range config.SelectorFields {
+		f, ok := selectors.Fields[name]
+		if !ok {
+			continue
+		}
+
+		compiled := field{name: name, Field: f}
+
+		if f.Regex != "" {
+			regex, err := regexp.Compile(f.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex for field %q: %w", name, err)
+			}
+			compiled.regex = regex
+		}
+
+		g.fields = append(g.fields, compiled)
+	}
+
+	return g, nil
+}
+
+// GetUrls collects the listing links and the next pages of the given listing page.
+func (g *GenericSelectorScraper) GetUrls(ctx context.Context, url string) ([]string, []string) {
+	var (
+		realEstateUrls = []string{}
+		nextPages      = []string{}
+	)
+
+	c := collector.NewCollector(ctx, g.logger, g.options.Collector...)
+
+	c.OnHTML(g.selectors.ListingLink, func(e *colly.HTMLElement) {
+		select {
+		case <-ctx.Done():
+			g.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
+			return
+		default:
+			realEstateUrls = append(realEstateUrls, e.Request.AbsoluteURL(e.Attr("href")))
+		}
+	})
+
+	if g.selectors.Pagination != "" {
+		c.OnHTML(g.selectors.Pagination, func(e *colly.HTMLElement) {
+			select {
+			case <-ctx.Done():
+				g.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
+				return
+			default:
+				nextPages = append(nextPages, e.Request.AbsoluteURL(e.Attr("href")))
+			}
+		})
+	}
+
+	select {
+	case <-ctx.Done():
+		g.logger.Debug(fmt.Sprint("Stopping visit due to context cancellation:", ctx.Err()))
+		return nil, nil
+	default:
+		c.Visit(url)
+
+		return realEstateUrls, nextPages
+	}
+}
+
+// GetData gets all the configured fields from a given url.
+func (g *GenericSelectorScraper) GetData(ctx context.Context, ch chan<- contracts.RealEstate, re *contracts.RealEstate, url string) {
+	c := collector.NewCollector(ctx, g.logger, g.options.Collector...)
+
+	// Single valued fields keep the first match, like a reader of the page would.
+	matched := make(map[string]bool)
+
+	if g.options.Tracer != nil {
+		for _, name := range config.SelectorFields {
+			g.options.Tracer.Field(name, g.selectors.Fields[name].Selector)
+		}
+	}
+
+	for _, f := range g.fields {
+		g.setField(ctx, c, re, f, matched)
+	}
+
+	found := contracts.RealEstate{}
+	if g.options.StructuredDataFallback {
+		structured.OnHTML(ctx, c, g.logger, &found)
+	}
+
+	c.OnScraped(func(c *colly.Response) {
+		re.FillMissing(found)
+		ch <- *re
+	})
+
+	select {
+	case <-ctx.Done():
+		g.logger.Debug(fmt.Sprint("Stopping visit due to context cancellation:", ctx.Err()))
+	default:
+		re.Url = url
+		g.options.Hints.Apply(re)
+
+		c.Visit(url)
+	}
+}
+
+func (g *GenericSelectorScraper) setField(ctx context.Context, c *colly.Collector, r *contracts.RealEstate, f field, matched map[string]bool) {
+	c.OnHTML(f.Selector, func(e *colly.HTMLElement) {
+		select {
+		case <-ctx.Done():
+			g.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
+			return
+		default:
+			if matched[f.name] && !slices.Contains(multiValued, f.name) {
+				return
+			}
+
+			text, ok := extract(e, f)
+			if !ok {
+				g.trace(f, e.Text, r, contracts.ErrNoValue)
+				return
+			}
+
+			matched[f.name] = true
+
+			err := apply(r, f, text)
+			g.trace(f, text, r, err)
+
+			if err != nil {
+				g.logger.Error(fmt.Sprintf("Error while trying to parse real state %s: %v", f.name, err))
+			}
+		}
+	})
+}
+
+// trace reports to the tracer, if any, what the field became from the raw text.
+func (g *GenericSelectorScraper) trace(f field, raw string, r *contracts.RealEstate, err error) {
+	if g.options.Tracer != nil {
+		g.options.Tracer.Step(contracts.TraceStep{
+			Url:      r.Url,
+			Field:    f.name,
+			Selector: f.Selector,
+			Raw:      raw,
+			Value:    contracts.FieldValue(r, f.name),
+			Err:      err,
+		})
+	}
+}
+
+// extract reads the raw value of a field from the matched element.
+func extract(e *colly.HTMLElement, f field) (string, bool) {
+	var text string
+
+	switch {
+	case f.Attr != "":
+		text = e.Attr(f.Attr)
+		if f.Attr == "href" || f.Attr == "src" {
+			text = e.Request.AbsoluteURL(text)
+		}
+	case f.Remove != "":
+		node := e.DOM.Clone()
+		node.Find(f.Remove).Remove()
+		text = node.Text()
+	default:
+		text = e.Text
+	}
+
+	if f.regex != nil {
+		match := f.regex.FindStringSubmatch(text)
+		if match == nil {
+			return "", false
+		}
+
+		text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+	}
+
+	text = strings.TrimSpace(text)
+
+	return text, text != ""
+}
+
+// apply sends the extracted text to the setter of the field.
+func apply(r *contracts.RealEstate, f field, text string) error {
+	if f.name == "furnished" {
+		if len(f.Truthy) == 0 {
+			return r.SetFurnished(true)
+		}
+
+		return r.SetFurnished(slices.ContainsFunc(f.Truthy, func(v string) bool {
+			return strings.EqualFold(v, text)
+		}))
+	}
+
+	setter, ok := setters[f.name]
+	if !ok {
+		return errors.New("no setter for field " + f.name)
+	}
+
+	return setter(r, text)
+}
@@ -2,6 +2,7 @@ package perfil
 
 import (
 	"baia/internal/contracts"
+	"baia/internal/scraper/structured"
 	"baia/pkg/collector"
 	"context"
 	"fmt"
@@ -15,13 +16,16 @@ import (
 
 // PerfilScraper implements the RealEstateScraperInterface for the "Perfil" real estate website.
 type PerfilScraper struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	options contracts.ScraperOptions
 }
 
 // NewPerfilScraper creates a new instance of PerfilScraper.
-func NewPerfilScraper(logger *slog.Logger) scrapify.IScraper[contracts.RealEstate] {
+// The option hints take precedence over what is inferred from the listing URL.
+func NewPerfilScraper(logger *slog.Logger, options contracts.ScraperOptions) scrapify.IScraper[contracts.RealEstate] {
 	return &PerfilScraper{
-		logger: logger,
+		logger:  logger,
+		options: options,
 	}
 }
 
@@ -32,7 +36,7 @@ func (p *PerfilScraper) GetUrls(ctx context.Context, url string) ([]string, []st
 		nextPages      = []string{}
 	)
 
-	c := collector.NewCollector(p.logger)
+	c := collector.NewCollector(ctx, p.logger, p.options.Collector...)
 
 	c.OnHTML("div#grid div.listing-item a[href]", func(e *colly.HTMLElement) {
 		select {
@@ -67,7 +71,7 @@ func (p *PerfilScraper) GetUrls(ctx context.Context, url string) ([]string, []st
 
 // GetRealEstate gets all the data from a given url
 func (p *PerfilScraper) GetData(ctx context.Context, ch chan<- contracts.RealEstate, re *contracts.RealEstate, url string) {
-	c := collector.NewCollector(p.logger)
+	c := collector.NewCollector(ctx, p.logger, p.options.Collector...)
 
 	p.SetRealEstateCode(ctx, c, re)
 	p.SetRealEstateName(ctx, c, re)
@@ -84,7 +88,13 @@ func (p *PerfilScraper) GetData(ctx context.Context, ch chan<- contracts.RealEst
 	p.SetRealEstatePhotos(ctx, c, re)
 	p.SetRealEstateTags(ctx, c, re)
 
+	found := contracts.RealEstate{}
+	if p.options.StructuredDataFallback {
+		structured.OnHTML(ctx, c, p.logger, &found)
+	}
+
 	c.OnScraped(func(c *colly.Response) {
+		re.FillMissing(found)
 		ch <- *re
 	})
 
@@ -109,25 +119,70 @@ func (p *PerfilScraper) GetData(ctx context.Context, ch chan<- contracts.RealEst
 			re.Type = contracts.Land
 		}
 
+		p.options.Hints.Apply(re)
+
 		c.Visit(url)
 	}
 }
 
+// Selectors of the fields of a detail page.
+const (
+	codeSelector         = "div.property-title h2 span.imovel-codigo"
+	nameSelector         = "div.property-title"
+	descriptionSelector  = "div#text-0 div p"
+	priceSelector        = "div.valor-imovel span"
+	bedroomsSelector     = "div.property-title span a span:nth-child(1)"
+	bathroomsSelector    = "#conteudo div.container div div.col-lg-8.col-md-7 div ul li.banheiros span"
+	areaSelector         = "div.property-description ul.listing-features li.area span"
+	garageSpacesSelector = "div.property-description ul.listing-features li.vagas span"
+	citySelector         = "div.property-title span a span[data-tag='address']"
+	furnishedSelector    = "div.property-description ul.listing-features li.mobilia span"
+	photosSelector       = "img.sp-image"
+	tagsSelector         = "ul.property-features li"
+)
+
+// declare tells the tracer, if any, the selector a field is extracted with.
+func (p *PerfilScraper) declare(field, selector string) {
+	if p.options.Tracer != nil {
+		p.options.Tracer.Field(field, selector)
+	}
+}
+
+// trace reports to the tracer, if any, what a field became from the raw text
+// matched by its selector.
+func (p *PerfilScraper) trace(field, selector, raw string, r *contracts.RealEstate, err error) {
+	if p.options.Tracer != nil {
+		p.options.Tracer.Step(contracts.TraceStep{
+			Url:      r.Url,
+			Field:    field,
+			Selector: selector,
+			Raw:      raw,
+			Value:    contracts.FieldValue(r, field),
+			Err:      err,
+		})
+	}
+}
+
 func (p *PerfilScraper) SetRealEstateCode(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-title h2 span.imovel-codigo", func(e *colly.HTMLElement) {
+	p.declare("code", codeSelector)
+
+	c.OnHTML(codeSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
 			code := strings.TrimSpace(strings.Replace(e.Text, "Cód.", "", 1))
-			r.SetCode(code)
+			err := r.SetCode(code)
+			p.trace("code", codeSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateName(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-title", func(e *colly.HTMLElement) {
+	p.declare("name", nameSelector)
+
+	c.OnHTML(nameSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
@@ -138,31 +193,38 @@ func (p *PerfilScraper) SetRealEstateName(ctx context.Context, c *colly.Collecto
 
 			span.Remove()
 
-			r.SetName(h2.Text())
+			err := r.SetName(h2.Text())
+			p.trace("name", nameSelector, h2.Text(), r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateDescription(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div#text-0 div p", func(e *colly.HTMLElement) {
+	p.declare("description", descriptionSelector)
+
+	c.OnHTML(descriptionSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
-			r.SetDescription(e.Text)
+			err := r.SetDescription(e.Text)
+			p.trace("description", descriptionSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstatePrice(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.valor-imovel span", func(e *colly.HTMLElement) {
+	p.declare("price", priceSelector)
+
+	c.OnHTML(priceSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
 			err := r.SetPrice(e.Text)
+			p.trace("price", priceSelector, e.Text, r, err)
 
 			if err != nil {
 				p.logger.Error(fmt.Sprint("Error while trying to parse real state price:", err))
@@ -173,7 +235,9 @@ func (p *PerfilScraper) SetRealEstatePrice(ctx context.Context, c *colly.Collect
 }
 
 func (p *PerfilScraper) SetRealEstateBedrooms(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-title span a span:nth-child(1)", func(e *colly.HTMLElement) {
+	p.declare("bedrooms", bedroomsSelector)
+
+	c.OnHTML(bedroomsSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
@@ -182,54 +246,68 @@ func (p *PerfilScraper) SetRealEstateBedrooms(ctx context.Context, c *colly.Coll
 			re := regexp.MustCompile(`\d+`)
 			match := re.FindString(e.Text)
 
+			var err error
 			if match != "" {
-				r.SetBedrooms(match)
+				err = r.SetBedrooms(match)
 			}
+			p.trace("bedrooms", bedroomsSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateBathrooms(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("#conteudo div.container div div.col-lg-8.col-md-7 div ul li.banheiros span", func(e *colly.HTMLElement) {
+	p.declare("bathrooms", bathroomsSelector)
+
+	c.OnHTML(bathroomsSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
-			r.SetBathrooms(e.Text)
+			err := r.SetBathrooms(e.Text)
+			p.trace("bathrooms", bathroomsSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateArea(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-description ul.listing-features li.area span", func(e *colly.HTMLElement) {
+	p.declare("area", areaSelector)
+
+	c.OnHTML(areaSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
-			r.SetArea(e.Text)
+			err := r.SetArea(e.Text)
+			p.trace("area", areaSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateGarageSpaces(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-description ul.listing-features li.vagas span", func(e *colly.HTMLElement) {
+	p.declare("garageSpaces", garageSpacesSelector)
+
+	c.OnHTML(garageSpacesSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
-			r.SetGarageSpaces(e.Text)
+			err := r.SetGarageSpaces(e.Text)
+			p.trace("garageSpaces", garageSpacesSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateDistrict(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
+	p.declare("district", "")
 }
 
 func (p *PerfilScraper) SetRealEstateCity(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-title span a span[data-tag='address']", func(e *colly.HTMLElement) {
+	p.declare("city", citySelector)
+
+	c.OnHTML(citySelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
@@ -241,48 +319,59 @@ func (p *PerfilScraper) SetRealEstateCity(ctx context.Context, c *colly.Collecto
 
 			i.Remove()
 
-			r.SetCity(strings.Split(span.Text(), " / ")[0])
+			err := r.SetCity(strings.Split(span.Text(), " / ")[0])
+			p.trace("city", citySelector, span.Text(), r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateFurnished(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("div.property-description ul.listing-features li.mobilia span", func(e *colly.HTMLElement) {
+	p.declare("furnished", furnishedSelector)
+
+	c.OnHTML(furnishedSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
 			isFurnished := e.Text == "Semi" || e.Text == "Sim"
-			r.SetFurnished(isFurnished)
+			err := r.SetFurnished(isFurnished)
+			p.trace("furnished", furnishedSelector, e.Text, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateYearBuilt(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
+	p.declare("yearBuilt", "")
 }
 
 func (p *PerfilScraper) SetRealEstatePhotos(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("img.sp-image", func(e *colly.HTMLElement) {
+	p.declare("photos", photosSelector)
+
+	c.OnHTML(photosSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
 			src := e.Attr("src")
-			r.SetPhoto(src)
+			err := r.SetPhoto(src)
+			p.trace("photos", photosSelector, src, r, err)
 		}
 	})
 }
 
 func (p *PerfilScraper) SetRealEstateTags(ctx context.Context, c *colly.Collector, r *contracts.RealEstate) {
-	c.OnHTML("ul.property-features li", func(e *colly.HTMLElement) {
+	p.declare("tags", tagsSelector)
+
+	c.OnHTML(tagsSelector, func(e *colly.HTMLElement) {
 		select {
 		case <-ctx.Done():
 			p.logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
 			return
 		default:
-			r.SetTag(e.Text)
+			err := r.SetTag(e.Text)
+			p.trace("tags", tagsSelector, e.Text, r, err)
 		}
 	})
 }
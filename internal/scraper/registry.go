@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/scraper/generic"
+	"baia/internal/scraper/perfil"
+	"baia/pkg/collector"
+
+	"github.com/ricardocastanho/scrapify"
+)
+
+// Factory builds the scraper used for one seed of a configured source.
+type Factory func(logger *slog.Logger, source config.Source, options contracts.ScraperOptions) (scrapify.IScraper[contracts.RealEstate], error)
+
+// Registry maps the scraper names used in the sources file to their constructors.
+type Registry struct {
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry with every scraper implementation of the project.
+func NewRegistry() *Registry {
+	r := &Registry{
+		factories: make(map[string]Factory),
+	}
+
+	r.Register("perfil", func(logger *slog.Logger, source config.Source, options contracts.ScraperOptions) (scrapify.IScraper[contracts.RealEstate], error) {
+		return perfil.NewPerfilScraper(logger, options), nil
+	})
+
+	r.Register("generic", func(logger *slog.Logger, source config.Source, options contracts.ScraperOptions) (scrapify.IScraper[contracts.RealEstate], error) {
+		if source.Selectors == nil {
+			return nil, errors.New("the generic scraper requires a selectors block")
+		}
+		return generic.NewGenericSelectorScraper(logger, *source.Selectors, options)
+	})
+
+	// The structured scraper only needs the listing selectors; every field
+	// comes from the schema.org data of the detail page.
+	r.Register("structured", func(logger *slog.Logger, source config.Source, options contracts.ScraperOptions) (scrapify.IScraper[contracts.RealEstate], error) {
+		if source.Selectors == nil {
+			return nil, errors.New("the structured scraper requires a selectors block with the listing link")
+		}
+
+		selectors := *source.Selectors
+		selectors.Fields = nil
+		options.StructuredDataFallback = true
+
+		return generic.NewGenericSelectorScraper(logger, selectors, options)
+	})
+
+	return r
+}
+
+// Register adds a scraper constructor under the given name, replacing any previous one.
+func (r *Registry) Register(name string, factory Factory) {
+	r.factories[name] = factory
+}
+
+// Names returns the registered scraper names in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scraper builds the scraper of the source for one seed. The collector options
+// are added to the ones every scraper of the source uses.
+func (r *Registry) Scraper(logger *slog.Logger, source config.Source, seed config.Seed, collectorOptions ...collector.Option) (scrapify.IScraper[contracts.RealEstate], error) {
+	options := source.ScraperOptions(seed)
+	options.Collector = append(options.Collector, collectorOptions...)
+
+	return r.Build(logger, source, options)
+}
+
+// Build creates the scraper of the source with the given options, for callers
+// that need more control over them than Scraper gives.
+func (r *Registry) Build(logger *slog.Logger, source config.Source, options contracts.ScraperOptions) (scrapify.IScraper[contracts.RealEstate], error) {
+	factory, ok := r.factories[source.Scraper]
+	if !ok {
+		return nil, fmt.Errorf("source %q: unknown scraper %q (available: %v)", source.Name, source.Scraper, r.Names())
+	}
+
+	s, err := factory(logger.With("source", source.Name), source, options)
+	if err != nil {
+		return nil, fmt.Errorf("source %q: failed to build scraper: %w", source.Name, err)
+	}
+
+	return s, nil
+}
+
+// Strategies turns every seed of the source into a scrapify strategy.
+// The collector options are added to the ones every scraper of the source uses.
+func (r *Registry) Strategies(logger *slog.Logger, source config.Source, collectorOptions ...collector.Option) ([]scrapify.ScraperStrategy[contracts.RealEstate], error) {
+	strategies := make([]scrapify.ScraperStrategy[contracts.RealEstate], 0, len(source.Seeds))
+
+	for _, seed := range source.Seeds {
+		s, err := r.Scraper(logger, source, seed, collectorOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		strategies = append(strategies, scrapify.ScraperStrategy[contracts.RealEstate]{
+			Scraper: s,
+			Url:     seed.Url,
+		})
+	}
+
+	return strategies, nil
+}
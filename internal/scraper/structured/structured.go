@@ -0,0 +1,310 @@
+package structured
+
+import (
+	"baia/internal/contracts"
+	"baia/internal/utils/brparse"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// residenceTypes maps the schema.org residence types to the real estate types.
+var residenceTypes = map[string]string{
+	"Apartment":             contracts.Apartment,
+	"ApartmentComplex":      contracts.Apartment,
+	"House":                 contracts.House,
+	"SingleFamilyResidence": contracts.House,
+	"Landform":              contracts.Land,
+}
+
+// ignoredTypes are the schema.org types whose name, description and images
+// describe something other than the listing itself.
+var ignoredTypes = []string{
+	"PostalAddress",
+	"GeoCoordinates",
+	"Organization",
+	"RealEstateAgent",
+	"BreadcrumbList",
+	"ListItem",
+	"WebSite",
+	"WebPage",
+}
+
+// OnHTML registers on the collector the handlers that read the JSON-LD and
+// microdata of a detail page into found. Values already in found are kept,
+// so the first occurrence of a property in the page wins.
+func OnHTML(ctx context.Context, c *colly.Collector, logger *slog.Logger, found *contracts.RealEstate) {
+	c.OnHTML("script[type='application/ld+json']", func(e *colly.HTMLElement) {
+		select {
+		case <-ctx.Done():
+			logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
+			return
+		default:
+			var data any
+
+			if err := json.Unmarshal([]byte(e.Text), &data); err != nil {
+				logger.Debug(fmt.Sprint("Ignoring invalid JSON-LD block: ", err))
+				return
+			}
+
+			walk(data, found)
+		}
+	})
+
+	c.OnHTML("[itemscope]:not([itemprop])", func(e *colly.HTMLElement) {
+		select {
+		case <-ctx.Done():
+			logger.Debug(fmt.Sprint("Stopping collection due to context cancellation:", ctx.Err()))
+			return
+		default:
+			walk(microdata(e.DOM), found)
+		}
+	})
+}
+
+// microdata converts an itemscope element into the same shape as a JSON-LD object.
+func microdata(scope *goquery.Selection) map[string]any {
+	item := make(map[string]any)
+
+	if itemtype, ok := scope.Attr("itemtype"); ok {
+		item["@type"] = itemtype[strings.LastIndex(itemtype, "/")+1:]
+	}
+
+	scope.Find("[itemprop]").Each(func(_ int, prop *goquery.Selection) {
+		if !prop.Parent().Closest("[itemscope]").IsSelection(scope) {
+			return
+		}
+
+		var value any
+
+		if _, nested := prop.Attr("itemscope"); nested {
+			value = microdata(prop)
+		} else if content, ok := prop.Attr("content"); ok {
+			value = content
+		} else if href, ok := prop.Attr("href"); ok {
+			value = href
+		} else if src, ok := prop.Attr("src"); ok {
+			value = src
+		} else {
+			value = strings.TrimSpace(prop.Text())
+		}
+
+		for _, name := range strings.Fields(prop.AttrOr("itemprop", "")) {
+			if current, ok := item[name]; ok {
+				if list, isList := current.([]any); isList {
+					item[name] = append(list, value)
+				} else {
+					item[name] = []any{current, value}
+				}
+				continue
+			}
+			item[name] = value
+		}
+	})
+
+	return item
+}
+
+// walk visits every object of the structured data and maps the known
+// schema.org properties onto the real estate.
+func walk(node any, r *contracts.RealEstate) {
+	switch v := node.(type) {
+	case []any:
+		for _, item := range v {
+			walk(item, r)
+		}
+	case map[string]any:
+		apply(v, r)
+
+		// Keys are sorted so the first-wins rule does not depend on map order.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "@context" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			walk(v[key], r)
+		}
+	}
+}
+
+func apply(item map[string]any, r *contracts.RealEstate) {
+	types := toStrings(item["@type"])
+
+	for _, t := range types {
+		if mapped, ok := residenceTypes[t]; ok && r.Type == "" {
+			r.Type = mapped
+		}
+	}
+
+	isListing := !slices.ContainsFunc(types, func(t string) bool {
+		return slices.Contains(ignoredTypes, t)
+	})
+
+	if isListing {
+		setString(&r.Name, item["name"])
+		setString(&r.Description, item["description"])
+		setString(&r.Code, first(item["sku"], item["productID"], item["identifier"]))
+		setInt(&r.Bedrooms, first(item["numberOfBedrooms"], item["numberOfRooms"]))
+		setInt(&r.Bathrooms, first(item["numberOfBathroomsTotal"], item["numberOfFullBathrooms"]))
+		setInt(&r.YearBuilt, item["yearBuilt"])
+		setArea(&r.Area, item["floorSize"])
+
+		if len(r.Photos) == 0 {
+			r.Photos = images(item["image"])
+		}
+	}
+
+	if slices.Contains(types, "Offer") || slices.Contains(types, "AggregateOffer") {
+		setInt(&r.Price, first(item["price"], item["lowPrice"]))
+
+		if !r.ForSale && !r.ForRent {
+			function, _ := item["businessFunction"].(string)
+			if strings.Contains(function, "LeaseOut") {
+				r.ForRent = true
+			} else if strings.Contains(function, "Sell") {
+				r.ForSale = true
+			}
+		}
+	}
+
+	if slices.Contains(types, "PostalAddress") {
+		setString(&r.City, item["addressLocality"])
+	}
+
+	if slices.Contains(types, "GeoCoordinates") && r.Latitude == 0 && r.Longitude == 0 {
+		lat, latOk := toCoordinate(item["latitude"])
+		lng, lngOk := toCoordinate(item["longitude"])
+		if latOk && lngOk {
+			r.Latitude = lat
+			r.Longitude = lng
+		}
+	}
+}
+
+func setString(dst *string, value any) {
+	if *dst != "" {
+		return
+	}
+	if s, ok := value.(string); ok {
+		*dst = strings.TrimSpace(s)
+	}
+}
+
+func setInt(dst *int, value any) {
+	if *dst != 0 {
+		return
+	}
+	if n, ok := toNumber(value); ok {
+		*dst = int(n)
+	}
+}
+
+// setArea reads a QuantitativeValue, converting hectares to square meters.
+func setArea(dst *int, value any) {
+	if *dst != 0 {
+		return
+	}
+
+	quantity, ok := value.(map[string]any)
+	if !ok {
+		setInt(dst, value)
+		return
+	}
+
+	n, ok := toNumber(quantity["value"])
+	if !ok {
+		return
+	}
+
+	if unit, _ := quantity["unitCode"].(string); unit == "HAR" {
+		n *= brparse.SquareMetersPerHectare
+	}
+
+	*dst = int(n)
+}
+
+func images(value any) []string {
+	var urls []string
+
+	switch v := value.(type) {
+	case string:
+		urls = append(urls, v)
+	case []any:
+		for _, item := range v {
+			urls = append(urls, images(item)...)
+		}
+	case map[string]any:
+		if url, ok := first(v["url"], v["contentUrl"]).(string); ok {
+			urls = append(urls, url)
+		}
+	}
+
+	return urls
+}
+
+// toNumber accepts JSON numbers and numeric strings written the Brazilian
+// way, like "450.000", "450.000,00" or "R$ 1,2 mi".
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := brparse.Number(v)
+		return n, err == nil
+	case map[string]any:
+		return toNumber(v["value"])
+	}
+
+	return 0, false
+}
+
+// toCoordinate accepts JSON numbers and numeric strings written the machine
+// way, like "-29.6842", which coordinates always are.
+func toCoordinate(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+func toStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func first(values ...any) any {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
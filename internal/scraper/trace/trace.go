@@ -0,0 +1,215 @@
+// Package trace keeps, prints and passes on what scrapers report through
+// contracts.Tracer about how they extract each field of a page.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"baia/internal/contracts"
+)
+
+// FieldReport is everything traced for one field.
+type FieldReport struct {
+	Name     string                `json:"name"`
+	Selector string                `json:"selector"`
+	Steps    []contracts.TraceStep `json:"steps"`
+}
+
+// Report is a contracts.Tracer that keeps the steps of a page, field by field, in the
+// order the fields were declared.
+type Report struct {
+	mutex  sync.Mutex
+	fields []*FieldReport
+}
+
+// NewReport creates an empty report.
+func NewReport() *Report {
+	return &Report{}
+}
+
+func (r *Report) Field(name, selector string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.fields = append(r.fields, &FieldReport{Name: name, Selector: selector, Steps: []contracts.TraceStep{}})
+}
+
+func (r *Report) Step(step contracts.TraceStep) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, f := range r.fields {
+		if f.Name == step.Field && f.Selector == step.Selector {
+			f.Steps = append(f.Steps, step)
+			return
+		}
+	}
+
+	// Steps of undeclared fields are kept rather than lost.
+	r.fields = append(r.fields, &FieldReport{Name: step.Field, Selector: step.Selector, Steps: []contracts.TraceStep{step}})
+}
+
+// Fields returns the report of every field.
+func (r *Report) Fields() []FieldReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	list := make([]FieldReport, 0, len(r.fields))
+	for _, f := range r.fields {
+		list = append(list, *f)
+	}
+
+	return list
+}
+
+// Write prints the report with one block per field: its selector, then every
+// match with the raw text and the parsed value or the setter error.
+func (r *Report) Write(w io.Writer) error {
+	var sb strings.Builder
+
+	for _, f := range r.Fields() {
+		switch {
+		case f.Selector == "":
+			fmt.Fprintf(&sb, "%s: not extracted by this scraper\n", f.Name)
+			continue
+		case len(f.Steps) == 0:
+			fmt.Fprintf(&sb, "%s: %s\n    NO MATCH\n", f.Name, f.Selector)
+			continue
+		}
+
+		fmt.Fprintf(&sb, "%s: %s\n", f.Name, f.Selector)
+
+		for _, step := range f.Steps {
+			fmt.Fprintf(&sb, "    raw:   %q\n", truncate(step.Raw))
+			if step.Err != nil {
+				fmt.Fprintf(&sb, "    error: %v\n", step.Err)
+			} else {
+				fmt.Fprintf(&sb, "    value: %s\n", truncate(formatValue(step.Value)))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// MarshalJSON encodes the report with the setter errors as text.
+func (r *Report) MarshalJSON() ([]byte, error) {
+	type step struct {
+		contracts.TraceStep
+		Error string `json:"error,omitempty"`
+	}
+	type field struct {
+		Name     string `json:"name"`
+		Selector string `json:"selector"`
+		Steps    []step `json:"steps"`
+	}
+
+	fields := []field{}
+	for _, f := range r.Fields() {
+		steps := make([]step, 0, len(f.Steps))
+		for _, s := range f.Steps {
+			converted := step{TraceStep: s}
+			if s.Err != nil {
+				converted.Error = s.Err.Error()
+			}
+			steps = append(steps, converted)
+		}
+		fields = append(fields, field{Name: f.Name, Selector: f.Selector, Steps: steps})
+	}
+
+	return json.Marshal(fields)
+}
+
+// Tee returns a tracer that passes everything it receives to every tracer.
+func Tee(tracers ...contracts.Tracer) contracts.Tracer {
+	return tee(tracers)
+}
+
+type tee []contracts.Tracer
+
+func (t tee) Field(name, selector string) {
+	for _, tracer := range t {
+		tracer.Field(name, selector)
+	}
+}
+
+func (t tee) Step(step contracts.TraceStep) {
+	for _, tracer := range t {
+		tracer.Step(step)
+	}
+}
+
+// Pages is a contracts.Tracer that keeps the steps of every page by URL, until they are
+// taken, so what a listing was extracted from can be kept along with it.
+type Pages struct {
+	mutex sync.Mutex
+	pages map[string][]contracts.TraceStep
+}
+
+// NewPages creates a tracer that keeps no page yet.
+func NewPages() *Pages {
+	return &Pages{pages: make(map[string][]contracts.TraceStep)}
+}
+
+func (p *Pages) Field(name, selector string) {}
+
+func (p *Pages) Step(step contracts.TraceStep) {
+	if step.Url == "" {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pages[step.Url] = append(p.pages[step.Url], step)
+}
+
+// Take returns the steps of the page and forgets them.
+func (p *Pages) Take(url string) []contracts.TraceStep {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	steps := p.pages[url]
+	delete(p.pages, url)
+
+	return steps
+}
+
+// Reset forgets the steps of every page, like the ones of pages whose listing
+// was never handled.
+func (p *Pages) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.pages = make(map[string][]contracts.TraceStep)
+}
+
+// RawTexts returns, by field, the raw texts matched by the steps.
+func RawTexts(steps []contracts.TraceStep) map[string][]string {
+	texts := make(map[string][]string)
+	for _, step := range steps {
+		texts[step.Field] = append(texts[step.Field], step.Raw)
+	}
+	return texts
+}
+
+func formatValue(v any) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(content)
+}
+
+func truncate(s string) string {
+	runes := []rune(strings.Join(strings.Fields(s), " "))
+	if len(runes) > 100 {
+		return string(runes[:97]) + "..."
+	}
+	return string(runes)
+}
@@ -0,0 +1,43 @@
+package scrapertest_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"baia/internal/scraper"
+	"baia/internal/scrapertest"
+)
+
+// TestGolden runs every case below internal/scraper against its recorded
+// pages, the way fixtures verify does.
+func TestGolden(t *testing.T) {
+	root := filepath.Join("..", "scraper")
+
+	dirs, err := scrapertest.Cases(root)
+	if err != nil {
+		t.Fatalf("failed to find cases: %v", err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("no cases found")
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := scraper.NewRegistry()
+
+	for _, dir := range dirs {
+		name, _ := filepath.Rel(root, dir)
+
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			diff, err := scrapertest.Verify(context.Background(), logger, registry, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != "" {
+				t.Errorf("result differs from %s, run go run ./cmd/fixtures update if the change is intended:\n%s", scrapertest.GoldenFile, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package scrapertest runs scrapers against recorded pages and compares what
+// they extract with golden files, so every scraper gets regression coverage
+// without touching the network.
+//
+// A case is a directory with a sources file holding one source with one seed,
+// the recorded pages of that seed and its detail pages, and the golden file:
+//
+//	sources.yaml
+//	pages/*.html
+//	golden.json
+package scrapertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"baia/internal/config"
+	"baia/internal/contracts"
+	"baia/internal/scraper"
+	"baia/pkg/collector"
+)
+
+const (
+	SourcesFile = "sources.yaml"
+	PagesDir    = "pages"
+	GoldenFile  = "golden.json"
+)
+
+// Result is what a scraper extracts from the seed page of a case.
+type Result struct {
+	Url         string                 `json:"url"`
+	Listings    []string               `json:"listings"`
+	NextPages   []string               `json:"nextPages"`
+	RealEstates []contracts.RealEstate `json:"realEstates"`
+}
+
+// Run scrapes the seed page of the case and every listing found on it,
+// fetching pages through the given transport.
+func Run(ctx context.Context, logger *slog.Logger, registry *scraper.Registry, dir string, transport http.RoundTripper) (*Result, error) {
+	cfg, err := config.Load(filepath.Join(dir, SourcesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Sources) != 1 || len(cfg.Sources[0].Seeds) != 1 {
+		return nil, errors.New("a case must have exactly one source with one seed")
+	}
+
+	strategies, err := registry.Strategies(logger, cfg.Sources[0], collector.WithTransport(transport))
+	if err != nil {
+		return nil, err
+	}
+
+	strategy := strategies[0]
+	urls, nextPages := strategy.Scraper.GetUrls(ctx, strategy.Url)
+
+	result := &Result{
+		Url:         strategy.Url,
+		Listings:    urls,
+		NextPages:   nextPages,
+		RealEstates: []contracts.RealEstate{},
+	}
+
+	for _, url := range urls {
+		ch := make(chan contracts.RealEstate, 1)
+		re := contracts.RealEstate{}
+
+		strategy.Scraper.GetData(ctx, ch, &re, url)
+
+		select {
+		case data := <-ch:
+			result.RealEstates = append(result.RealEstates, data)
+		default:
+			return nil, fmt.Errorf("no data extracted from %s", url)
+		}
+	}
+
+	return result, nil
+}
+
+// Verify runs the case against its recorded pages and returns the differences
+// from the golden file, or an empty string when they match.
+func Verify(ctx context.Context, logger *slog.Logger, registry *scraper.Registry, dir string) (string, error) {
+	want, err := os.ReadFile(filepath.Join(dir, GoldenFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read golden file: %w", err)
+	}
+
+	result, err := Run(ctx, logger, registry, dir, &FixtureTransport{Dir: filepath.Join(dir, PagesDir)})
+	if err != nil {
+		return "", err
+	}
+
+	got, err := encode(result)
+	if err != nil {
+		return "", err
+	}
+
+	return diff(string(want), string(got)), nil
+}
+
+// Record fetches the pages of the case from the network, replacing the
+// recorded ones, and rewrites the golden file with what the scraper extracts.
+func Record(ctx context.Context, logger *slog.Logger, registry *scraper.Registry, dir string) error {
+	pages := filepath.Join(dir, PagesDir)
+
+	if err := os.RemoveAll(pages); err != nil {
+		return fmt.Errorf("failed to clean recorded pages: %w", err)
+	}
+	if err := os.MkdirAll(pages, 0o755); err != nil {
+		return fmt.Errorf("failed to create pages directory: %w", err)
+	}
+
+	result, err := Run(ctx, logger, registry, dir, &RecordingTransport{Dir: pages, Next: http.DefaultTransport})
+	if err != nil {
+		return err
+	}
+
+	content, err := encode(result)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, GoldenFile), content, 0o644)
+}
+
+// Update rewrites the golden file of the case with what the scraper extracts
+// from the recorded pages, for when the extracted data changes on purpose.
+func Update(ctx context.Context, logger *slog.Logger, registry *scraper.Registry, dir string) error {
+	result, err := Run(ctx, logger, registry, dir, &FixtureTransport{Dir: filepath.Join(dir, PagesDir)})
+	if err != nil {
+		return err
+	}
+
+	content, err := encode(result)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, GoldenFile), content, 0o644)
+}
+
+// Cases returns every case directory below root, in lexical order.
+func Cases(root string) ([]string, error) {
+	var dirs []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == SourcesFile && strings.Contains(path, string(filepath.Separator)+"testdata"+string(filepath.Separator)) {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+
+	sort.Strings(dirs)
+
+	return dirs, err
+}
+
+func encode(result *Result) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(result); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// diff returns a line based diff of two texts, with "-" for lines only in want
+// and "+" for lines only in got.
+func diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&sb, "-%d: %s\n", i+1, a[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "+%d: %s\n", j+1, b[j])
+			j++
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,93 @@
+package scrapertest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FixtureName returns the file name under which the page of the URL is recorded.
+// Names stay readable, falling back to a hash suffix when the URL is too long.
+func FixtureName(u *url.URL) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, u.Host+u.RequestURI())
+
+	if len(name) > 120 {
+		sum := sha1.Sum([]byte(u.String()))
+		name = name[:100] + "_" + hex.EncodeToString(sum[:])[:12]
+	}
+
+	return name + ".html"
+}
+
+// FixtureTransport serves recorded pages from a directory instead of the network.
+// Pages that were not recorded are answered with 404 Not Found.
+type FixtureTransport struct {
+	Dir string
+}
+
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := os.ReadFile(filepath.Join(t.Dir, FixtureName(req.URL)))
+	if os.IsNotExist(err) {
+		return response(req, http.StatusNotFound, []byte("fixture not found: "+req.URL.String())), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	return response(req, http.StatusOK, body), nil
+}
+
+// RecordingTransport fetches pages through Next and saves every successful
+// response into a directory, in the layout FixtureTransport reads.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.Next.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(t.Dir, FixtureName(req.URL)), body, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	return res, nil
+}
+
+func response(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"text/html; charset=utf-8"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
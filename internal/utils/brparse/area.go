@@ -0,0 +1,277 @@
+package brparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Unit is a unit of area.
+type Unit int
+
+const (
+	SquareMeters Unit = iota
+	Hectares
+	// Alqueires are the alqueire paulista, the one most listings mean.
+	Alqueires
+	// AlqueiresMineiros are twice as large.
+	AlqueiresMineiros
+)
+
+// Square meters in a unit of area.
+const (
+	SquareMetersPerHectare         = 10_000
+	SquareMetersPerAlqueire        = 24_200
+	SquareMetersPerAlqueireMineiro = 48_400
+)
+
+func (u Unit) String() string {
+	switch u {
+	case SquareMeters:
+		return "m²"
+	case Hectares:
+		return "ha"
+	case Alqueires:
+		return "alqueires"
+	default:
+		return "alqueires mineiros"
+	}
+}
+
+// Area is a measure of area in some unit. Its zero value is no area.
+type Area struct {
+	Value float64
+	Unit  Unit
+}
+
+// SquareMeters returns the area in square meters.
+func (a Area) SquareMeters() float64 {
+	switch a.Unit {
+	case Hectares:
+		return a.Value * SquareMetersPerHectare
+	case Alqueires:
+		return a.Value * SquareMetersPerAlqueire
+	case AlqueiresMineiros:
+		return a.Value * SquareMetersPerAlqueireMineiro
+	default:
+		return a.Value
+	}
+}
+
+// Areas are the areas a listing shows: the built one, which includes the
+// private and usable areas of apartments, and the total one, which includes
+// the land.
+type Areas struct {
+	Built Area
+	Total Area
+}
+
+// Main returns the area a listing is compared by: the built one, or the total
+// one for land and listings that show only that one.
+func (a Areas) Main() Area {
+	if a.Built.Value > 0 {
+		return a.Built
+	}
+	return a.Total
+}
+
+// unitPattern matches the units of area written after a number.
+var unitPattern = regexp.MustCompile(`^\s*(m²|m2|mts?²|mts?2|metros?\s+quadrados?|hectares?|ha|alqueires?|alq)\.?`)
+
+// lengthPattern matches the units of length written after the last side of
+// dimensions like "12 x 30 m".
+var lengthPattern = regexp.MustCompile(`^\s*(m|mts?|metros?)\.?`)
+
+// builtLabels and totalLabels are the words that label areas, folded.
+var (
+	builtLabels = []string{"constru", "privativ", "util", "uteis", "edificad"}
+	totalLabels = []string{"total", "terreno", "lote"}
+)
+
+// measure is an area found in a text, with the bytes it spans along with the
+// label after its unit, if any.
+type measure struct {
+	area       Area
+	label      string
+	start, end int
+}
+
+// ParseAreas parses the areas of a listing: "180,50 m²", "2 ha", "3
+// alqueires", "Área construída: 120 m² Área total: 300 m²", "120 m²
+// construídos / 450 m² de terreno" or the sides of a lot in meters, "12x30".
+// Numbers without a unit are square meters when the text has no unit at all,
+// and the lower end of ranges like "55 a 80 m²" is kept. An area without a
+// label is the built one, or else the total one.
+func ParseAreas(text string) (Areas, error) {
+	lower := strings.ToLower(text)
+
+	found, err := amounts(lower)
+	if err != nil {
+		return Areas{}, err
+	}
+
+	if len(found) == 0 {
+		return Areas{}, fmt.Errorf("%w in area %q", ErrNoNumber, text)
+	}
+
+	measures := make([]measure, 0, len(found))
+
+	skip := false
+	for i, a := range found {
+		if skip {
+			skip = false
+			continue
+		}
+
+		// "12x30" or "12 x 30 m" are the sides of a lot, in meters.
+		if i+1 < len(found) && isDimensions(lower[a.end:found[i+1].start]) {
+			next := found[i+1]
+			end := next.end
+			if _, unitEnd, ok := unitAfter(lower, end); ok {
+				end = unitEnd
+			} else if match := lengthPattern.FindStringIndex(lower[end:]); match != nil && !startsWithLetter(lower[end+match[1]:]) {
+				end += match[1]
+			}
+
+			label, labelEnd := labelAfter(lower, end)
+			measures = append(measures, measure{area: Area{Value: a.value * next.value, Unit: SquareMeters}, label: label, start: a.start, end: labelEnd})
+			skip = true
+			continue
+		}
+
+		unit, end, ok := unitAfter(lower, a.end)
+
+		// "55 a 80 m²" measures both ends in the unit of the last one, of
+		// which the lower end is kept.
+		if !ok && i+1 < len(found) && isRange(lower[a.end:found[i+1].start]) {
+			unit, end, ok = unitAfter(lower, found[i+1].end)
+			skip = ok
+		}
+		if !ok {
+			continue
+		}
+
+		label, labelEnd := labelAfter(lower, end)
+		measures = append(measures, measure{area: Area{Value: a.value, Unit: unit}, label: label, start: a.start, end: labelEnd})
+	}
+
+	// Texts with no unit, like "180,50", are in square meters.
+	if len(measures) == 0 {
+		return Areas{Built: Area{Value: found[0].value, Unit: SquareMeters}}, nil
+	}
+
+	var areas Areas
+	var unlabeled []Area
+	previous := 0
+
+	for _, m := range measures {
+		label := m.label
+		if label == "" {
+			label = lastLabel(lower[previous:m.start])
+		}
+		previous = m.end
+
+		switch label {
+		case "built":
+			if areas.Built.Value == 0 {
+				areas.Built = m.area
+			}
+		case "total":
+			if areas.Total.Value == 0 {
+				areas.Total = m.area
+			}
+		default:
+			unlabeled = append(unlabeled, m.area)
+		}
+	}
+
+	for _, area := range unlabeled {
+		if areas.Built.Value == 0 {
+			areas.Built = area
+		} else if areas.Total.Value == 0 {
+			areas.Total = area
+		}
+	}
+
+	return areas, nil
+}
+
+// isDimensions reports whether the text between two numbers makes them the
+// sides of an area, as in "12x30" or "12 x 30".
+func isDimensions(between string) bool {
+	between = strings.TrimSpace(between)
+	return between == "x" || between == "×"
+}
+
+// unitAfter returns the unit of area at the byte offset of the lowercased
+// text and the offset where it ends.
+func unitAfter(lower string, offset int) (Unit, int, bool) {
+	match := unitPattern.FindStringSubmatchIndex(lower[offset:])
+	if match == nil || startsWithLetter(lower[offset+match[1]:]) {
+		return 0, 0, false
+	}
+
+	end := offset + match[1]
+	word := lower[offset+match[2] : offset+match[3]]
+
+	switch {
+	case strings.HasPrefix(word, "h"):
+		return Hectares, end, true
+	case strings.HasPrefix(word, "alq"):
+		if next, nextEnd := nextWord(lower, end); strings.HasPrefix(fold(next), "mineiro") || strings.HasPrefix(fold(next), "geometrico") {
+			return AlqueiresMineiros, nextEnd, true
+		}
+		return Alqueires, end, true
+	default:
+		return SquareMeters, end, true
+	}
+}
+
+// labelAfter returns the label of the area whose unit ends at the byte offset
+// of the lowercased text, as in "120 m² construídos" or "450 m² de terreno",
+// and the offset where it ends.
+func labelAfter(lower string, offset int) (string, int) {
+	word, end := nextWord(lower, offset)
+	if word == "de" || word == "do" || word == "da" {
+		word, end = nextWord(lower, end)
+	}
+
+	if label := labelOf(word); label != "" {
+		return label, end
+	}
+	return "", offset
+}
+
+// lastLabel returns the label of the last word of the text that labels an
+// area, as in "Área construída:".
+func lastLabel(lower string) string {
+	label := ""
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if l := labelOf(word); l != "" {
+			label = l
+		}
+	}
+	return label
+}
+
+// labelOf returns "built" or "total" for the words that label areas, or an
+// empty string.
+func labelOf(word string) string {
+	word = fold(word)
+	if word == "" {
+		return ""
+	}
+
+	for _, label := range builtLabels {
+		if strings.HasPrefix(word, label) {
+			return "built"
+		}
+	}
+	for _, label := range totalLabels {
+		if strings.HasPrefix(word, label) {
+			return "total"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package brparse_test
+
+import (
+	"errors"
+	"testing"
+
+	"baia/internal/utils/brparse"
+)
+
+func TestParseAreas(t *testing.T) {
+	m2 := func(value float64) brparse.Area { return brparse.Area{Value: value, Unit: brparse.SquareMeters} }
+
+	tests := []struct {
+		text string
+		want brparse.Areas
+	}{
+		{"180,50 m²", brparse.Areas{Built: m2(180.5)}},
+		{"180,50", brparse.Areas{Built: m2(180.5)}},
+		{"95 m2", brparse.Areas{Built: m2(95)}},
+		{"1.200 metros quadrados", brparse.Areas{Built: m2(1200)}},
+		{"2 ha", brparse.Areas{Built: brparse.Area{Value: 2, Unit: brparse.Hectares}}},
+		{"2,5 hectares", brparse.Areas{Built: brparse.Area{Value: 2.5, Unit: brparse.Hectares}}},
+		{"3 alqueires", brparse.Areas{Built: brparse.Area{Value: 3, Unit: brparse.Alqueires}}},
+		{"3 alqueires mineiros", brparse.Areas{Built: brparse.Area{Value: 3, Unit: brparse.AlqueiresMineiros}}},
+		{"55 a 80 m²", brparse.Areas{Built: m2(55)}},
+		{"Área construída: 120 m² Área total: 300 m²", brparse.Areas{Built: m2(120), Total: m2(300)}},
+		{"Área total: 300 m² Área privativa: 120 m²", brparse.Areas{Built: m2(120), Total: m2(300)}},
+		{"120 m² construídos / 450 m² de terreno", brparse.Areas{Built: m2(120), Total: m2(450)}},
+		{"Terreno de 12x30", brparse.Areas{Total: m2(360)}},
+		{"12x30", brparse.Areas{Built: m2(360)}},
+		{"12 x 30 m", brparse.Areas{Built: m2(360)}},
+		{"12,5 x 40 metros", brparse.Areas{Built: m2(500)}},
+	}
+
+	for _, tt := range tests {
+		got, err := brparse.ParseAreas(tt.text)
+		if err != nil {
+			t.Errorf("ParseAreas(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAreas(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseAreasWithoutNumber(t *testing.T) {
+	if _, err := brparse.ParseAreas("Consulte"); !errors.Is(err, brparse.ErrNoNumber) {
+		t.Errorf("ParseAreas without digits returned %v, want %v", err, brparse.ErrNoNumber)
+	}
+}
+
+func TestAreaSquareMeters(t *testing.T) {
+	tests := []struct {
+		area brparse.Area
+		want float64
+	}{
+		{brparse.Area{Value: 180, Unit: brparse.SquareMeters}, 180},
+		{brparse.Area{Value: 2, Unit: brparse.Hectares}, 20000},
+		{brparse.Area{Value: 1, Unit: brparse.Alqueires}, 24200},
+		{brparse.Area{Value: 1, Unit: brparse.AlqueiresMineiros}, 48400},
+	}
+
+	for _, tt := range tests {
+		if got := tt.area.SquareMeters(); got != tt.want {
+			t.Errorf("%v %v = %v m², want %v", tt.area.Value, tt.area.Unit, got, tt.want)
+		}
+	}
+}
+
+func TestAreasMain(t *testing.T) {
+	land := brparse.Areas{Total: brparse.Area{Value: 450}}
+	if got := land.Main(); got.Value != 450 {
+		t.Errorf("Main of an area with only the total one = %v, want 450", got.Value)
+	}
+
+	house := brparse.Areas{Built: brparse.Area{Value: 120}, Total: brparse.Area{Value: 450}}
+	if got := house.Main(); got.Value != 120 {
+		t.Errorf("Main of an area with both = %v, want the built one, 120", got.Value)
+	}
+}
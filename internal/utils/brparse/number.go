@@ -0,0 +1,181 @@
+// Package brparse parses the numbers, prices and areas of Brazilian listings,
+// as people write them: "R$ 1.250.000,00", "R$ 1,2 mi", "a partir de R$ 450
+// mil", "Sob consulta", "180,50 m²" or "2 ha".
+package brparse
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"baia/internal/utils"
+)
+
+// ErrNoNumber is returned for texts with no number in them.
+var ErrNoNumber = errors.New("no number found")
+
+// magnitudes are the words that multiply the number before them.
+var magnitudes = map[string]float64{
+	"mil":     1e3,
+	"mi":      1e6,
+	"milhao":  1e6,
+	"milhoes": 1e6,
+	"bi":      1e9,
+	"bilhao":  1e9,
+	"bilhoes": 1e9,
+}
+
+// rangeSeparators are the words between the two ends of a range, once the
+// currency and the spaces around them are dropped.
+var rangeSeparators = []string{"a", "-", "–", "ate", "e"}
+
+var digitsPattern = regexp.MustCompile(`\d[\d.,]*`)
+
+// amount is a number found in a text, scaled by the magnitude after it.
+type amount struct {
+	value float64
+	// scale is the magnitude the number was multiplied by, 1 when none.
+	scale float64
+	// start and end are the bytes of the text the number and its magnitude
+	// span.
+	start, end int
+}
+
+// amounts returns the numbers of the text, in order.
+func amounts(text string) ([]amount, error) {
+	var found []amount
+
+	for _, match := range digitsPattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+
+		// Separators ending the number are punctuation, as in "R$ 450.000,".
+		digits := strings.TrimRight(text[start:end], ".,")
+		end = start + len(digits)
+
+		value, err := parseDigits(digits)
+		if err != nil {
+			return nil, err
+		}
+
+		a := amount{value: value, scale: 1, start: start, end: end}
+
+		word, wordEnd := nextWord(text, end)
+		if scale, ok := magnitudes[fold(word)]; ok {
+			a.value *= scale
+			a.scale = scale
+			a.end = wordEnd
+		}
+
+		found = append(found, a)
+	}
+
+	return found, nil
+}
+
+// parseDigits parses digits grouped by dots and commas. The last separator is
+// the decimal one when both are used. A lone comma is always decimal, while a
+// lone dot is decimal unless three digits follow it, as in "450.000".
+func parseDigits(digits string) (float64, error) {
+	dots, commas := strings.Count(digits, "."), strings.Count(digits, ",")
+
+	switch {
+	case dots > 0 && commas > 0:
+		decimal := ","
+		if strings.LastIndex(digits, ".") > strings.LastIndex(digits, ",") {
+			decimal = "."
+		}
+		grouping := strings.Trim(".,", decimal)
+
+		digits = strings.ReplaceAll(digits, grouping, "")
+		digits = strings.Replace(digits, decimal, ".", 1)
+	case commas > 1:
+		digits = strings.ReplaceAll(digits, ",", "")
+	case commas == 1:
+		digits = strings.Replace(digits, ",", ".", 1)
+	case dots > 1 || (dots == 1 && len(digits)-strings.Index(digits, ".") == 4):
+		digits = strings.ReplaceAll(digits, ".", "")
+	}
+
+	value, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", digits)
+	}
+
+	return value, nil
+}
+
+// nextWord returns the word that follows the byte offset of the text, after
+// any spaces, and the offset where it ends.
+func nextWord(text string, offset int) (string, int) {
+	rest := strings.TrimLeftFunc(text[offset:], unicode.IsSpace)
+	start := len(text) - len(rest)
+
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(rest)
+	}
+
+	return rest[:end], start + end
+}
+
+// fold lowercases the text and drops its accents and spaces, so words are
+// compared the way people misspell them.
+func fold(text string) string {
+	return utils.NormalizeCityName(text)
+}
+
+// isRange reports whether the text between two numbers makes them the ends of
+// a range, as in "R$ 300 mil a R$ 450 mil" or "55 - 80 m²".
+func isRange(between string) bool {
+	between = strings.TrimSpace(strings.ReplaceAll(strings.ToLower(between), "r$", ""))
+	between = fold(between)
+
+	for _, separator := range rangeSeparators {
+		if between == separator {
+			return true
+		}
+	}
+	return false
+}
+
+// Number parses the first number of the text, written the Brazilian way and
+// possibly followed by a magnitude: "1.234,5", "450 mil" or "1,2 mi".
+func Number(text string) (float64, error) {
+	found, err := amounts(text)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(found) == 0 {
+		return 0, fmt.Errorf("%w in %q", ErrNoNumber, text)
+	}
+
+	return found[0].value, nil
+}
+
+// Integer parses the first number of the text, which must be whole, like the
+// counts of rooms in "3 dormitórios" or a year.
+func Integer(text string) (int, error) {
+	value, err := Number(text)
+	if err != nil {
+		return 0, err
+	}
+
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("%v is not a whole number", value)
+	}
+
+	return int(value), nil
+}
+
+// startsWithLetter reports whether the text starts with a letter, which tells
+// a unit apart from the beginning of a longer word.
+func startsWithLetter(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return unicode.IsLetter(r)
+}
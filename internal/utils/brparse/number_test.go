@@ -0,0 +1,75 @@
+package brparse_test
+
+import (
+	"errors"
+	"testing"
+
+	"baia/internal/utils/brparse"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"450.000", 450000},
+		{"450.000,00", 450000},
+		{"1.250.000,50", 1250000.5},
+		{"1,250,000.50", 1250000.5},
+		{"180,50", 180.5},
+		{"2.5", 2.5},
+		{"450 mil", 450000},
+		{"1,2 mi", 1200000},
+		{"2 milhões", 2000000},
+		{"R$ 450.000,", 450000},
+		{"3 dormitórios", 3},
+	}
+
+	for _, tt := range tests {
+		got, err := brparse.Number(tt.text)
+		if err != nil {
+			t.Errorf("Number(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Number(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNumberWithoutDigits(t *testing.T) {
+	if _, err := brparse.Number("sem número"); !errors.Is(err, brparse.ErrNoNumber) {
+		t.Errorf("Number without digits returned %v, want %v", err, brparse.ErrNoNumber)
+	}
+}
+
+func TestInteger(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int
+		wantErr bool
+	}{
+		{text: "3 dormitórios", want: 3},
+		{text: "2015", want: 2015},
+		{text: "1.500", want: 1500},
+		{text: "2,5", wantErr: true},
+		{text: "nenhum", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := brparse.Integer(tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Integer(%q) = %d, want an error", tt.text, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Integer(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Integer(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package brparse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrZeroPrice is returned for prices of zero, which sites show when the price
+// was left blank rather than when the listing is free.
+var ErrZeroPrice = errors.New("price is zero")
+
+// PriceKind tells how a listing states its price.
+type PriceKind int
+
+const (
+	// PriceFixed is a single price.
+	PriceFixed PriceKind = iota
+	// PriceFrom is the lowest price of a development, as in "a partir de".
+	PriceFrom
+	// PriceRange is a price between Min and Max.
+	PriceRange
+	// PriceOnRequest is a price the agency only tells when asked, as in "Sob
+	// consulta".
+	PriceOnRequest
+)
+
+func (k PriceKind) String() string {
+	switch k {
+	case PriceFixed:
+		return "fixed"
+	case PriceFrom:
+		return "from"
+	case PriceRange:
+		return "range"
+	default:
+		return "on request"
+	}
+}
+
+// Price is the price of a listing in R$. Min and Max are the same for fixed
+// prices, Max is 0 for prices from Min and both are 0 for prices on request.
+type Price struct {
+	Kind PriceKind
+	Min  float64
+	Max  float64
+}
+
+// onRequestPhrases are the texts of the prices told on request, folded.
+var onRequestPhrases = []string{"sobconsulta", "consulte", "consultar", "acombinar"}
+
+// fromPhrases are the texts before the lowest price of a development, folded.
+var fromPhrases = []string{"apartirde", "desde"}
+
+// ParsePrice parses the price of a listing: "R$ 1.250.000,00", "R$ 1,2 mi",
+// "a partir de R$ 450 mil", "R$ 300 mil a R$ 450 mil" or "Sob consulta". Of
+// prices followed by other amounts, like a condominium fee, only the first is
+// kept. Prices of zero, like "R$ 0,00", are an error.
+func ParsePrice(text string) (Price, error) {
+	found, err := amounts(text)
+	if err != nil {
+		return Price{}, err
+	}
+
+	if len(found) == 0 {
+		folded := fold(text)
+		for _, phrase := range onRequestPhrases {
+			if strings.Contains(folded, phrase) {
+				return Price{Kind: PriceOnRequest}, nil
+			}
+		}
+
+		return Price{}, fmt.Errorf("%w in price %q", ErrNoNumber, text)
+	}
+
+	first := found[0]
+
+	if len(found) > 1 && isRange(text[first.end:found[1].start]) {
+		last := found[1]
+
+		// "R$ 300 a 450 mil" scales both ends.
+		if first.scale == 1 && last.scale > 1 && first.value < 1000 {
+			first.value *= last.scale
+		}
+
+		if first.value == 0 && last.value == 0 {
+			return Price{}, fmt.Errorf("%w in price %q", ErrZeroPrice, text)
+		}
+
+		return Price{Kind: PriceRange, Min: min(first.value, last.value), Max: max(first.value, last.value)}, nil
+	}
+
+	if first.value == 0 {
+		return Price{}, fmt.Errorf("%w in price %q", ErrZeroPrice, text)
+	}
+
+	price := Price{Kind: PriceFixed, Min: first.value, Max: first.value}
+
+	before := fold(text[:first.start])
+	for _, phrase := range fromPhrases {
+		if strings.Contains(before, phrase) {
+			price.Kind, price.Max = PriceFrom, 0
+		}
+	}
+
+	return price, nil
+}
@@ -0,0 +1,62 @@
+package brparse_test
+
+import (
+	"errors"
+	"testing"
+
+	"baia/internal/utils/brparse"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text string
+		want brparse.Price
+	}{
+		{"R$ 1.250.000,00", brparse.Price{Kind: brparse.PriceFixed, Min: 1250000, Max: 1250000}},
+		{"R$ 450.000", brparse.Price{Kind: brparse.PriceFixed, Min: 450000, Max: 450000}},
+		{"450000", brparse.Price{Kind: brparse.PriceFixed, Min: 450000, Max: 450000}},
+		{"R$ 1,2 mi", brparse.Price{Kind: brparse.PriceFixed, Min: 1200000, Max: 1200000}},
+		{"R$ 1,5 milhão", brparse.Price{Kind: brparse.PriceFixed, Min: 1500000, Max: 1500000}},
+		{"R$ 450 mil", brparse.Price{Kind: brparse.PriceFixed, Min: 450000, Max: 450000}},
+		{"R$ 1.800,00 + condomínio R$ 350,00", brparse.Price{Kind: brparse.PriceFixed, Min: 1800, Max: 1800}},
+		{"A partir de R$ 450 mil", brparse.Price{Kind: brparse.PriceFrom, Min: 450000}},
+		{"Desde R$ 299.900,00", brparse.Price{Kind: brparse.PriceFrom, Min: 299900}},
+		{"R$ 300 mil a R$ 450 mil", brparse.Price{Kind: brparse.PriceRange, Min: 300000, Max: 450000}},
+		{"R$ 300 a 450 mil", brparse.Price{Kind: brparse.PriceRange, Min: 300000, Max: 450000}},
+		{"R$ 1.200,00 - R$ 1.500,00", brparse.Price{Kind: brparse.PriceRange, Min: 1200, Max: 1500}},
+		{"Sob consulta", brparse.Price{Kind: brparse.PriceOnRequest}},
+		{"Consulte-nos", brparse.Price{Kind: brparse.PriceOnRequest}},
+		{"Valor a combinar", brparse.Price{Kind: brparse.PriceOnRequest}},
+	}
+
+	for _, tt := range tests {
+		got, err := brparse.ParsePrice(tt.text)
+		if err != nil {
+			t.Errorf("ParsePrice(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePrice(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParsePriceErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"R$ 0,00", brparse.ErrZeroPrice},
+		{"R$ 0", brparse.ErrZeroPrice},
+		{"A partir de R$ 0,00", brparse.ErrZeroPrice},
+		{"", brparse.ErrNoNumber},
+		{"Imóvel à venda", brparse.ErrNoNumber},
+	}
+
+	for _, tt := range tests {
+		got, err := brparse.ParsePrice(tt.text)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("ParsePrice(%q) = %+v, %v, want error %v", tt.text, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Shutdown splits the stop of a process in two stages. Stop is canceled by the
+// first SIGINT or SIGTERM, or when the parent context is done, telling the
+// process to start no new work. Work is canceled when the grace period after
+// that is over, or on a second signal, abandoning the work in flight. Work
+// ignores the deadline of the parent, so a timeout gets the grace period too.
+type Shutdown struct {
+	Stop context.Context
+	Work context.Context
+
+	mutex  sync.Mutex
+	signal os.Signal
+}
+
+// NewShutdown watches the signals on top of the parent context, which usually
+// comes from NewCancelableContext or NewTimeoutContext. onSignal, when not nil,
+// is called when the stop starts, with a nil signal when the parent is done,
+// and again when the work is abandoned.
+// The returned function releases the signals and cancels both contexts.
+func NewShutdown(parent context.Context, grace time.Duration, onSignal func(sig os.Signal, abandoning bool)) (*Shutdown, context.CancelFunc) {
+	work, cancelWork := context.WithCancel(context.WithoutCancel(parent))
+	stop, cancelStop := context.WithCancel(parent)
+
+	s := &Shutdown{Stop: stop, Work: work}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+
+	go func() {
+		var sig os.Signal
+
+		select {
+		case sig = <-signals:
+			s.mutex.Lock()
+			s.signal = sig
+			s.mutex.Unlock()
+		case <-parent.Done():
+		case <-done:
+			return
+		}
+
+		if onSignal != nil {
+			onSignal(sig, false)
+		}
+		cancelStop()
+
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case sig = <-signals:
+		case <-timer.C:
+		case <-done:
+			return
+		}
+
+		if onSignal != nil {
+			onSignal(sig, true)
+		}
+		cancelWork()
+	}()
+
+	return s, func() {
+		signal.Stop(signals)
+		close(done)
+		cancelStop()
+		cancelWork()
+	}
+}
+
+// Signal returns the signal that started the shutdown, or nil when none arrived.
+func (s *Shutdown) Signal() os.Signal {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.signal
+}
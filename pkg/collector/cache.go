@@ -0,0 +1,215 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrCacheMiss is returned in offline mode for the requests not in the cache.
+var ErrCacheMiss = errors.New("not in the cache")
+
+// Cache keeps HTTP responses on disk, each in a file named after the hash of
+// the method and URL of its request, so pages can be fetched again without
+// hitting the site.
+// Only successful GET responses are cached. Entries older than the TTL are
+// fetched again, and the oldest entries are evicted once the cache is over its
+// max size; zero means no limit for both. An offline cache never goes to the
+// network: it serves every entry it has, expired or not, and fails on misses.
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+	offline bool
+
+	mutex sync.Mutex
+	size  int64
+}
+
+// NewCache opens the cache in dir, creating it if needed.
+func NewCache(dir string, ttl time.Duration, maxSize int64, offline bool) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := &Cache{dir: dir, ttl: ttl, maxSize: maxSize, offline: offline}
+
+	for _, entry := range c.entries() {
+		c.size += entry.size
+	}
+
+	return c, nil
+}
+
+// Transport wraps the transport so its responses are served from the cache.
+func (c *Cache) Transport(transport http.RoundTripper) http.RoundTripper {
+	return &cachedTransport{cache: c, transport: transport}
+}
+
+func (c *Cache) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	key := hex.EncodeToString(sum[:])
+
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// load reads the cached response of the request, if any and still fresh.
+func (c *Cache) load(req *http.Request) (*http.Response, bool) {
+	path := c.path(req)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+
+	if !c.offline && c.ttl > 0 && time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(content)), req)
+	if err != nil {
+		return nil, false
+	}
+
+	return resp, true
+}
+
+// store writes the dump of the response to the request to the cache.
+func (c *Cache) store(req *http.Request, dump []byte) error {
+	path := c.path(req)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Readers never see a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(dump); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if info, err := os.Stat(path); err == nil {
+		c.size -= info.Size()
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	c.size += int64(len(dump))
+
+	if c.maxSize > 0 && c.size > c.maxSize {
+		c.evict()
+	}
+
+	return nil
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (c *Cache) entries() []cacheEntry {
+	var entries []cacheEntry
+
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil {
+			entries = append(entries, cacheEntry{path: path, size: info.Size(), modTime: info.ModTime()})
+		}
+
+		return nil
+	})
+
+	return entries
+}
+
+// evict removes the oldest entries until the cache fits its max size.
+func (c *Cache) evict() {
+	entries := c.entries()
+	slices.SortFunc(entries, func(a, b cacheEntry) int {
+		return a.modTime.Compare(b.modTime)
+	})
+
+	c.size = 0
+	for _, entry := range entries {
+		c.size += entry.size
+	}
+
+	for _, entry := range entries {
+		if c.size <= c.maxSize {
+			break
+		}
+
+		if err := os.Remove(entry.path); err == nil {
+			c.size -= entry.size
+		}
+	}
+}
+
+type cachedTransport struct {
+	cache     *Cache
+	transport http.RoundTripper
+}
+
+func (t *cachedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.cache
+
+	if req.Method == http.MethodGet {
+		if resp, ok := c.load(req); ok {
+			return resp, nil
+		}
+	}
+
+	if c.offline {
+		return nil, fmt.Errorf("%w: %s %s", ErrCacheMiss, req.Method, req.URL)
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil || req.Method != http.MethodGet || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	// Dumping reads the body, and leaves a copy of it to the caller.
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	// A response that could not be cached is served all the same.
+	c.store(req, dump)
+
+	return resp, nil
+}
@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+
+	"baia/pkg/page"
+)
+
+// Changes makes the requests of the pages fetched before conditional, and
+// tracks which of them did not change. Pages answered with 304 Not Modified,
+// or with the same content as before, reach the collector as a 304, which it
+// does not parse.
+type Changes struct {
+	mutex     sync.Mutex
+	known     map[string]page.Validators
+	fetched   map[string]page.Validators
+	unchanged []string
+}
+
+// NewChanges creates a tracker that knows no page yet.
+func NewChanges() *Changes {
+	return &Changes{
+		known:   make(map[string]page.Validators),
+		fetched: make(map[string]page.Validators),
+	}
+}
+
+// Reset starts tracking again from the validators of the pages fetched before,
+// by URL.
+func (c *Changes) Reset(known map[string]page.Validators) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.known = known
+	c.fetched = make(map[string]page.Validators)
+	c.unchanged = nil
+}
+
+// Fetched returns the validators of the page as fetched since the last reset.
+func (c *Changes) Fetched(url string) (page.Validators, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.fetched[url]
+
+	return v, ok
+}
+
+// TakeUnchanged returns the URLs of the pages found unchanged since the last
+// call.
+func (c *Changes) TakeUnchanged() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	urls := c.unchanged
+	c.unchanged = nil
+
+	return urls
+}
+
+func (c *Changes) lookup(url string) (page.Validators, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	v, ok := c.known[url]
+
+	return v, ok
+}
+
+func (c *Changes) record(url string, v page.Validators, unchanged bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if unchanged {
+		c.unchanged = append(c.unchanged, url)
+	} else {
+		c.fetched[url] = v
+	}
+}
+
+// Transport wraps the transport so its requests go through the tracker.
+func (c *Changes) Transport(transport http.RoundTripper) http.RoundTripper {
+	return &changesTransport{changes: c, transport: transport}
+}
+
+type changesTransport struct {
+	changes   *Changes
+	transport http.RoundTripper
+}
+
+func (t *changesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.transport.RoundTrip(req)
+	}
+
+	url := req.URL.String()
+	known, ok := t.changes.lookup(url)
+
+	if ok && (known.ETag != "" || known.LastModified != "") {
+		req = req.Clone(req.Context())
+		if known.ETag != "" {
+			req.Header.Set("If-None-Match", known.ETag)
+		}
+		if known.LastModified != "" {
+			req.Header.Set("If-Modified-Since", known.LastModified)
+		}
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		if ok {
+			t.changes.record(url, known, true)
+		}
+		return resp, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	fetched := page.Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentHash:  hex.EncodeToString(sum[:]),
+	}
+
+	if ok && known.ContentHash == fetched.ContentHash {
+		t.changes.record(url, known, true)
+
+		return &http.Response{
+			Status:     "304 Not Modified",
+			StatusCode: http.StatusNotModified,
+			Proto:      resp.Proto,
+			ProtoMajor: resp.ProtoMajor,
+			ProtoMinor: resp.ProtoMinor,
+			Header:     resp.Header,
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	t.changes.record(url, fetched, false)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
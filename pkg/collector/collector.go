@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -9,18 +10,52 @@ import (
 	"github.com/gocolly/colly/v2"
 )
 
-func NewCollector(logger *slog.Logger) *colly.Collector {
+// DefaultUserAgent identifies the crawler honestly, with where to learn about it.
+const DefaultUserAgent = "baia/1.0 (+https://github.com/ricardocastanho/baia)"
+
+// Option customizes a collector after its defaults are applied, in the same
+// spirit as colly's own collector options. It is a plain function type, so
+// the scraper options in contracts can carry options without importing this
+// package.
+type Option = func(c *colly.Collector)
+
+// WithTransport replaces the HTTP transport of the collector, which lets
+// callers serve pages from somewhere other than the network.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *colly.Collector) {
+		c.WithTransport(transport)
+	}
+}
+
+// WithUserAgent sets the user agent the collector identifies with.
+func WithUserAgent(userAgent string) Option {
+	return func(c *colly.Collector) {
+		c.UserAgent = userAgent
+	}
+}
+
+// NewTransport creates the HTTP transport collectors use by default.
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		IdleConnTimeout:       10 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+	}
+}
+
+const contextKey = "collector.context"
+
+// NewCollector creates a collector with the project defaults and the given
+// options, for visits made on behalf of ctx.
+func NewCollector(ctx context.Context, logger *slog.Logger, options ...Option) *colly.Collector {
 	c := colly.NewCollector(
-		colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Safari/537.36"),
+		colly.UserAgent(DefaultUserAgent),
 		colly.MaxDepth(2),
 	)
 
-	c.WithTransport(&http.Transport{
-		IdleConnTimeout:       10 * time.Second,
-		ResponseHeaderTimeout: 10 * time.Second,
-	})
+	c.WithTransport(NewTransport())
 
 	c.OnRequest(func(r *colly.Request) {
+		r.Ctx.Put(contextKey, ctx)
 		logger.Info(fmt.Sprint("Visiting: ", r.URL.String()))
 	})
 
@@ -28,5 +63,17 @@ func NewCollector(logger *slog.Logger) *colly.Collector {
 		logger.Info(fmt.Sprint("Finished: ", r.Request.URL.String()))
 	})
 
+	for _, option := range options {
+		option(c)
+	}
+
 	return c
 }
+
+// requestContext returns the context the request was made on behalf of.
+func requestContext(r *colly.Request) context.Context {
+	if ctx, ok := r.Ctx.GetAny(contextKey).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
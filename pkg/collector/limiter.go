@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Limiter applies colly limit rules to the requests of every collector that
+// shares it. Colly enforces its own limits per collector, which does not help
+// scrapers creating a collector per page.
+// Like in colly, the first rule matching the host of a request applies, its
+// parallelism counts the requests in flight to every host it matches, and a
+// request only gives its slot back once the delay, plus a random part up to
+// the random delay, is over.
+type Limiter struct {
+	rules []*limit
+}
+
+type limit struct {
+	rule  *colly.LimitRule
+	slots chan struct{}
+}
+
+// NewLimiter checks the rules and creates a limiter enforcing them.
+func NewLimiter(rules ...*colly.LimitRule) (*Limiter, error) {
+	l := &Limiter{}
+
+	for _, rule := range rules {
+		if err := rule.Init(); err != nil {
+			return nil, err
+		}
+
+		l.rules = append(l.rules, &limit{
+			rule:  rule,
+			slots: make(chan struct{}, max(rule.Parallelism, 1)),
+		})
+	}
+
+	return l, nil
+}
+
+// Transport wraps the transport so its requests go through the limiter.
+func (l *Limiter) Transport(transport http.RoundTripper) http.RoundTripper {
+	return &limitedTransport{limiter: l, transport: transport}
+}
+
+func (l *Limiter) match(host string) *limit {
+	for _, limit := range l.rules {
+		if limit.rule.Match(host) {
+			return limit
+		}
+	}
+
+	return nil
+}
+
+// release gives the slot back once the delay of the rule is over.
+func (l *limit) release() {
+	delay := l.rule.Delay
+	if l.rule.RandomDelay > 0 {
+		delay += rand.N(l.rule.RandomDelay)
+	}
+
+	if delay <= 0 {
+		<-l.slots
+		return
+	}
+
+	time.AfterFunc(delay, func() { <-l.slots })
+}
+
+type limitedTransport struct {
+	limiter   *Limiter
+	transport http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.limiter.match(req.URL.Hostname())
+	if limit == nil {
+		return t.transport.RoundTrip(req)
+	}
+
+	select {
+	case limit.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		limit.release()
+		return nil, err
+	}
+
+	// The request is in flight until its body is read.
+	resp.Body = &limitedBody{ReadCloser: resp.Body, release: limit.release}
+
+	return resp, nil
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *limitedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
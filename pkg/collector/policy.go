@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Policy is how politely the collectors sharing it crawl: the user agent they
+// identify with, the limits of their requests, the robots.txt rules they
+// honour and how they retry failed fetches. A crawl should share one policy
+// between all its collectors.
+type Policy struct {
+	userAgent string
+	transport http.RoundTripper
+	robots    *Robots
+	retry     RetryPolicy
+}
+
+// NewPolicy creates a policy identifying with the user agent and applying the
+// limit rules to every request, including the ones for robots.txt files.
+// When the cache is not nil, the responses it has skip the limits.
+func NewPolicy(logger *slog.Logger, userAgent string, retry RetryPolicy, cache *Cache, rules ...*colly.LimitRule) (*Policy, error) {
+	limiter, err := NewLimiter(rules...)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := limiter.Transport(NewTransport())
+	if cache != nil {
+		transport = cache.Transport(transport)
+	}
+
+	return &Policy{
+		userAgent: userAgent,
+		transport: transport,
+		robots:    NewRobots(logger, &http.Client{Transport: transport, Timeout: 30 * time.Second}, userAgent),
+		retry:     retry,
+	}, nil
+}
+
+// SourceOptions are what differs between the sources sharing a policy.
+type SourceOptions struct {
+	// Logger logs the fetches that failed for good.
+	Logger *slog.Logger
+	// IgnoreRobotsTxt is only for sources that are explicitly allowed to.
+	IgnoreRobotsTxt bool
+	// Failures, when not nil, counts the fetches that failed for good.
+	Failures *Failures
+	// Changes, when not nil, makes the requests of known pages conditional.
+	Changes *Changes
+}
+
+// Options returns the collector options applying the policy to a source.
+func (p *Policy) Options(source SourceOptions) []Option {
+	transport := p.transport
+	if source.Changes != nil {
+		transport = source.Changes.Transport(transport)
+	}
+
+	options := []Option{
+		WithUserAgent(p.userAgent),
+		WithTransport(transport),
+		WithRetry(source.Logger, p.retry, source.Failures),
+	}
+
+	if !source.IgnoreRobotsTxt {
+		options = append(options, WithRobots(p.robots))
+	}
+
+	return options
+}
@@ -0,0 +1,238 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// FailureKind classifies why a page could not be fetched.
+type FailureKind string
+
+const (
+	// FailureTimeout is a request that timed out.
+	FailureTimeout FailureKind = "timeout"
+	// FailureNetwork is a request that got no response.
+	FailureNetwork FailureKind = "network"
+	// FailureThrottled is a 429 Too Many Requests response.
+	FailureThrottled FailureKind = "throttled"
+	// FailureServer is a 5xx response.
+	FailureServer FailureKind = "server"
+	// FailureClient is any other 4xx response, such as a listing that is gone.
+	FailureClient FailureKind = "client"
+	// FailureCacheMiss is a page missing from an offline cache.
+	FailureCacheMiss FailureKind = "cache-miss"
+	// FailureOther is any other error.
+	FailureOther FailureKind = "other"
+)
+
+// Retryable reports whether a fetch that failed this way may succeed later.
+func (k FailureKind) Retryable() bool {
+	switch k {
+	case FailureTimeout, FailureNetwork, FailureThrottled, FailureServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// Missed reports whether the page may still be there although it could not be
+// fetched, so what it lists must not be taken as gone. Pages missing from an
+// offline cache are not retried, but were never asked to the site.
+func (k FailureKind) Missed() bool {
+	return k.Retryable() || k == FailureCacheMiss
+}
+
+// Classify tells why a fetch failed from its response and error.
+func Classify(r *colly.Response, err error) FailureKind {
+	status := 0
+	if r != nil {
+		status = r.StatusCode
+	}
+
+	var netErr net.Error
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		return FailureThrottled
+	case status >= 500:
+		return FailureServer
+	case status >= 400:
+		return FailureClient
+	case errors.Is(err, ErrCacheMiss):
+		return FailureCacheMiss
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return FailureTimeout
+	case status == 0 && err != nil:
+		return FailureNetwork
+	default:
+		return FailureOther
+	}
+}
+
+// RetryPolicy tells how many times a fetch is attempted, and how long to wait
+// between attempts: the backoff doubles on every attempt up to the max backoff,
+// and half of it is random so that retries of many pages do not line up.
+// A Retry-After header is honoured instead, unless it asks for more than the
+// max backoff, which gives up on the page.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Delay returns how long to wait before the given attempt, the second one being
+// the first retry.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 2; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxBackoff)
+
+	if delay/2 <= 0 {
+		return delay
+	}
+
+	return delay/2 + rand.N(delay/2)
+}
+
+// retryAfter reads the Retry-After header of the response, in seconds or as a date.
+func retryAfter(r *colly.Response) (time.Duration, bool) {
+	if r == nil || r.Headers == nil {
+		return 0, false
+	}
+
+	value := r.Headers.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// FailedPages maps the URLs of the pages that could not be fetched to the kind
+// of their failure.
+type FailedPages map[string]FailureKind
+
+// Counts returns how many pages failed of each kind.
+func (p FailedPages) Counts() map[FailureKind]int {
+	counts := make(map[FailureKind]int)
+	for _, kind := range p {
+		counts[kind]++
+	}
+	return counts
+}
+
+// Failures records the fetches that failed for good.
+type Failures struct {
+	mutex sync.Mutex
+	pages FailedPages
+}
+
+// Add records a fetch of the URL that failed for good.
+func (f *Failures) Add(url string, kind FailureKind) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.pages == nil {
+		f.pages = make(FailedPages)
+	}
+	f.pages[url] = kind
+}
+
+// Take returns the pages that failed so far and starts recording again from
+// none.
+func (f *Failures) Take() FailedPages {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	pages := f.pages
+	f.pages = nil
+
+	return pages
+}
+
+const attemptKey = "collector.attempt"
+
+// WithRetry retries the fetches that failed in a way that may succeed later,
+// unless the visit is over before the next attempt, and logs and records in
+// failures the ones that failed for good.
+func WithRetry(logger *slog.Logger, policy RetryPolicy, failures *Failures) Option {
+	return func(c *colly.Collector) {
+		c.OnError(func(r *colly.Response, err error) {
+			// Unchanged pages are not parsed, but did not fail either.
+			if r.StatusCode == http.StatusNotModified {
+				return
+			}
+
+			attempt := 1
+			if n, ok := r.Ctx.GetAny(attemptKey).(int); ok {
+				attempt = n
+			}
+
+			kind := Classify(r, err)
+			url := r.Request.URL.String()
+
+			if kind.Retryable() && attempt < policy.MaxAttempts {
+				delay := policy.Delay(attempt + 1)
+				after, ok := retryAfter(r)
+
+				if !ok || after <= policy.MaxBackoff {
+					if ok {
+						delay = after
+					}
+
+					logger.Warn("Retrying page", "url", url, "kind", kind, "status", r.StatusCode, "attempt", attempt, "delay", delay, "error", err)
+
+					if wait(requestContext(r.Request), delay) {
+						r.Ctx.Put(attemptKey, attempt+1)
+
+						// The retried fetch fails through this handler again,
+						// so the error it returns is already taken care of.
+						r.Request.Retry()
+						return
+					}
+
+					logger.Warn("Giving up on page, the visit is over", "url", url, "kind", kind)
+				}
+			}
+
+			logger.Error("Failed to fetch page", "url", url, "kind", kind, "status", r.StatusCode, "attempts", attempt, "error", err)
+
+			if failures != nil {
+				failures.Add(url, kind)
+			}
+		})
+	}
+}
+
+// wait sleeps for the delay, unless the context is done first. It reports
+// whether the whole delay went by.
+func wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
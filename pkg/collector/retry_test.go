@@ -0,0 +1,152 @@
+package collector_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"baia/pkg/collector"
+
+	"github.com/gocolly/colly/v2"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		want   collector.FailureKind
+	}{
+		{"too many requests", http.StatusTooManyRequests, errors.New("Too Many Requests"), collector.FailureThrottled},
+		{"server error", http.StatusInternalServerError, errors.New("Internal Server Error"), collector.FailureServer},
+		{"bad gateway", http.StatusBadGateway, errors.New("Bad Gateway"), collector.FailureServer},
+		{"not found", http.StatusNotFound, errors.New("Not Found"), collector.FailureClient},
+		{"gone", http.StatusGone, errors.New("Gone"), collector.FailureClient},
+		{"cache miss", 0, fmt.Errorf("get page: %w", collector.ErrCacheMiss), collector.FailureCacheMiss},
+		{"deadline", 0, fmt.Errorf("get page: %w", context.DeadlineExceeded), collector.FailureTimeout},
+		{"network timeout", 0, timeoutError{}, collector.FailureTimeout},
+		{"connection refused", 0, errors.New("connection refused"), collector.FailureNetwork},
+		{"error with a response", http.StatusOK, errors.New("parse error"), collector.FailureOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collector.Classify(&colly.Response{StatusCode: tt.status}, tt.err); got != tt.want {
+				t.Errorf("Classify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelay(t *testing.T) {
+	policy := collector.RetryPolicy{MaxAttempts: 10, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{4, 4 * time.Second},
+		{5, 5 * time.Second},
+		{9, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("attempt ", tt.attempt), func(t *testing.T) {
+			for range 20 {
+				if got := policy.Delay(tt.attempt); got < tt.want/2 || got > tt.want {
+					t.Fatalf("Delay(%d) = %s, want between %s and %s", tt.attempt, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// response is what the test server answers to a request.
+type response struct {
+	status     int
+	retryAfter string
+}
+
+func TestWithRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []response
+		canceled  bool
+		requests  int
+		failure   collector.FailureKind
+	}{
+		{"success", []response{{status: 200}}, false, 1, ""},
+		{"server error then success", []response{{status: 503}, {status: 200}}, false, 2, ""},
+		{"server error for good", []response{{status: 500}}, false, 3, collector.FailureServer},
+		{"not found is not retried", []response{{status: 404}}, false, 1, collector.FailureClient},
+		{"not modified is no failure", []response{{status: 304}}, false, 1, ""},
+		{"throttled with a short Retry-After", []response{{status: 429, retryAfter: "0"}, {status: 200}}, false, 2, ""},
+		{"throttled with a Retry-After date", []response{{status: 429, retryAfter: time.Now().UTC().Format(http.TimeFormat)}, {status: 200}}, false, 2, ""},
+		{"throttled with a Retry-After over the max backoff", []response{{status: 429, retryAfter: "3600"}}, false, 1, collector.FailureThrottled},
+		{"throttled with an invalid Retry-After", []response{{status: 429, retryAfter: "soon"}, {status: 200}}, false, 2, ""},
+		{"visit over before the retry", []response{{status: 503}}, true, 1, collector.FailureServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mutex    sync.Mutex
+				requests int
+			)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				answer := tt.responses[min(requests, len(tt.responses)-1)]
+				requests++
+				mutex.Unlock()
+
+				if answer.retryAfter != "" {
+					w.Header().Set("Retry-After", answer.retryAfter)
+				}
+				w.WriteHeader(answer.status)
+			}))
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			policy := collector.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Second}
+			failures := &collector.Failures{}
+
+			c := collector.NewCollector(ctx, logger, collector.WithRetry(logger, policy, failures))
+			c.Visit(server.URL + "/imovel")
+			c.Wait()
+
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+
+			failed := failures.Take()
+			if got := failed[server.URL+"/imovel"]; got != tt.failure {
+				t.Errorf("failure = %q, want %q", got, tt.failure)
+			}
+			if tt.failure == "" && len(failed) > 0 {
+				t.Errorf("failures = %v, want none", failed)
+			}
+		})
+	}
+}
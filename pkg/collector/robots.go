@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/temoto/robotstxt"
+)
+
+// Robots honours the robots.txt files of the hosts crawled, fetching each file
+// once for every collector sharing it, where colly would fetch it again for
+// every collector.
+type Robots struct {
+	logger    *slog.Logger
+	client    *http.Client
+	userAgent string
+
+	mutex sync.Mutex
+	hosts map[string]*robotsHost
+}
+
+type robotsHost struct {
+	once sync.Once
+	data *robotstxt.RobotsData
+}
+
+// NewRobots creates a robots.txt checker for the given user agent, fetching
+// the files with the client.
+func NewRobots(logger *slog.Logger, client *http.Client, userAgent string) *Robots {
+	return &Robots{
+		logger:    logger,
+		client:    client,
+		userAgent: userAgent,
+		hosts:     make(map[string]*robotsHost),
+	}
+}
+
+// Allowed reports whether the robots.txt of the host lets the user agent fetch
+// the URL. Hosts whose robots.txt cannot be fetched allow everything.
+func (r *Robots) Allowed(u *url.URL) bool {
+	r.mutex.Lock()
+	host, ok := r.hosts[u.Host]
+	if !ok {
+		host = &robotsHost{}
+		r.hosts[u.Host] = host
+	}
+	r.mutex.Unlock()
+
+	host.once.Do(func() {
+		host.data = r.fetch(u)
+	})
+
+	if host.data == nil {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.Query().Encode()
+	}
+
+	return host.data.TestAgent(path, r.userAgent)
+}
+
+func (r *Robots) fetch(u *url.URL) *robotstxt.RobotsData {
+	robotsUrl := u.Scheme + "://" + u.Host + "/robots.txt"
+
+	req, err := http.NewRequest(http.MethodGet, robotsUrl, nil)
+	if err != nil {
+		r.logger.Warn("Failed to fetch robots.txt, allowing every page", "url", robotsUrl, "error", err)
+		return nil
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.logger.Warn("Failed to fetch robots.txt, allowing every page", "url", robotsUrl, "error", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	data, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		r.logger.Warn("Failed to parse robots.txt, allowing every page", "url", robotsUrl, "error", err)
+		return nil
+	}
+
+	return data
+}
+
+// WithRobots aborts the requests the robots.txt files disallow.
+func WithRobots(robots *Robots) Option {
+	return func(c *colly.Collector) {
+		c.OnRequest(func(req *colly.Request) {
+			if !robots.Allowed(req.URL) {
+				robots.logger.Info(fmt.Sprint("Disallowed by robots.txt: ", req.URL.String()))
+				req.Abort()
+			}
+		})
+	}
+}
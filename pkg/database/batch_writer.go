@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// BatchConfig holds the tuning of a BatchWriter. Zero values fall back to the defaults.
+type BatchConfig struct {
+	// Size is how many items are buffered before they are written.
+	Size int
+	// MaxAttempts is how many times a batch is tried when it fails with a transient error.
+	MaxAttempts int
+	// RetryDelay is the wait before the second attempt, doubled on every new attempt.
+	RetryDelay time.Duration
+	// Observe, when not nil, is called after every batch with its number of
+	// rows, how long its write took and the error of the write.
+	Observe func(rows int, took time.Duration, err error)
+}
+
+const (
+	DefaultBatchSize   = 100
+	DefaultMaxAttempts = 3
+	DefaultRetryDelay  = time.Millisecond * 500
+)
+
+// BatchWriter buffers items and writes them with a single query per batch.
+// The query receives the batch as the $rows parameter, usually consumed by UNWIND.
+type BatchWriter[T any] struct {
+	driver neo4j.DriverWithContext
+	query  string
+	toRow  func(T) map[string]any
+	report func(items []T, err error)
+	config BatchConfig
+	items  []T
+	mutex  sync.Mutex // Ensures a single flush at a time
+}
+
+// NewBatchWriter is a constructor that creates a new BatchWriter instance.
+// toRow converts an item into the row given to the query, and report, when not
+// nil, is called after every batch with its items and the error of the write.
+func NewBatchWriter[T any](driver neo4j.DriverWithContext, query string, toRow func(T) map[string]any, report func(items []T, err error), config BatchConfig) *BatchWriter[T] {
+	if config.Size <= 0 {
+		config.Size = DefaultBatchSize
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultRetryDelay
+	}
+
+	return &BatchWriter[T]{
+		driver: driver,
+		query:  query,
+		toRow:  toRow,
+		report: report,
+		config: config,
+	}
+}
+
+// Add buffers an item, writing the batch when it is full.
+func (w *BatchWriter[T]) Add(ctx context.Context, item T) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.items = append(w.items, item)
+
+	if len(w.items) < w.config.Size {
+		return nil
+	}
+
+	return w.flush(ctx)
+}
+
+// Flush writes the buffered items, if any.
+func (w *BatchWriter[T]) Flush(ctx context.Context) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.flush(ctx)
+}
+
+// Close writes the buffered items. It should be called on shutdown with a
+// context that is still alive, so pending items are not lost.
+func (w *BatchWriter[T]) Close(ctx context.Context) error {
+	return w.Flush(ctx)
+}
+
+func (w *BatchWriter[T]) flush(ctx context.Context) error {
+	if len(w.items) == 0 {
+		return nil
+	}
+
+	items := w.items
+	w.items = nil
+
+	rows := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, w.toRow(item))
+	}
+
+	start := time.Now()
+	err := w.write(ctx, rows)
+
+	if w.config.Observe != nil {
+		w.config.Observe(len(rows), time.Since(start), err)
+	}
+
+	if w.report != nil {
+		w.report(items, err)
+	}
+
+	return err
+}
+
+// write runs the query, retrying with exponential backoff while the error is transient.
+func (w *BatchWriter[T]) write(ctx context.Context, rows []map[string]any) error {
+	delay := w.config.RetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := w.run(ctx, rows)
+		if err == nil {
+			return nil
+		}
+
+		transient := neo4j.IsRetryable(err) || neo4j.IsTransactionExecutionLimit(err)
+
+		if attempt >= w.config.MaxAttempts || !transient {
+			return fmt.Errorf("failed to write batch of %d rows after %d attempts: %w", len(rows), attempt, err)
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return fmt.Errorf("failed to write batch of %d rows: %w", len(rows), ctx.Err())
+		}
+	}
+}
+
+func (w *BatchWriter[T]) run(ctx context.Context, rows []map[string]any) error {
+	session := w.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, w.query, map[string]any{"rows": rows})
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+		return nil, nil
+	})
+
+	return err
+}
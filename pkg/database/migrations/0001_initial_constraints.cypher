@@ -0,0 +1,31 @@
+// Constraints and indexes relied upon by the real estate MERGE queries, so
+// they do not scan whole labels nor race into duplicated nodes.
+CREATE CONSTRAINT migration_version IF NOT EXISTS
+FOR (m:Migration) REQUIRE m.version IS UNIQUE;
+
+CREATE CONSTRAINT real_estate_code IF NOT EXISTS
+FOR (r:RealEstate) REQUIRE r.code IS UNIQUE;
+
+CREATE CONSTRAINT real_estate_id IF NOT EXISTS
+FOR (r:RealEstate) REQUIRE r.id IS UNIQUE;
+
+CREATE CONSTRAINT agency_normalized_name IF NOT EXISTS
+FOR (a:Agency) REQUIRE a.normalizedName IS UNIQUE;
+
+CREATE CONSTRAINT estate_normalized_name IF NOT EXISTS
+FOR (e:Estate) REQUIRE e.normalizedName IS UNIQUE;
+
+CREATE CONSTRAINT city_normalized_name IF NOT EXISTS
+FOR (c:City) REQUIRE c.normalizedName IS UNIQUE;
+
+CREATE CONSTRAINT district_name IF NOT EXISTS
+FOR (d:District) REQUIRE d.name IS UNIQUE;
+
+CREATE CONSTRAINT price_id IF NOT EXISTS
+FOR (p:Price) REQUIRE p.id IS UNIQUE;
+
+CREATE INDEX real_estate_last_crawl_id IF NOT EXISTS
+FOR (r:RealEstate) ON (r.lastCrawlId);
+
+CREATE INDEX real_estate_type IF NOT EXISTS
+FOR (r:RealEstate) ON (r.type);
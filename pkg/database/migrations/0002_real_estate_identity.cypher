@@ -0,0 +1,59 @@
+// Listing codes are only unique within an agency. Real estates are now merged
+// by a key made of the normalized agency name and the code (or the URL when the
+// agency shows no code), so the uniqueness moves from code to key.
+DROP CONSTRAINT real_estate_code IF EXISTS;
+
+// Listings of different agencies that shared a code were merged into a single
+// node linked to every agency. Give each extra agency its own copy, keeping the
+// places and the price history the merged node had.
+MATCH (r:RealEstate)-[:SELLED_BY]->(a:Agency)
+WITH r, collect(a) AS agencies
+WHERE size(agencies) > 1
+UNWIND agencies[1..] AS agency
+MATCH (r)-[sold:SELLED_BY]->(agency)
+CREATE (copy:RealEstate)
+SET copy = properties(r), copy.id = randomUUID()
+FOREACH (_ IN CASE WHEN r:House THEN [1] ELSE [] END | SET copy:House)
+FOREACH (_ IN CASE WHEN r:Apartment THEN [1] ELSE [] END | SET copy:Apartment)
+FOREACH (_ IN CASE WHEN r:Land THEN [1] ELSE [] END | SET copy:Land)
+FOREACH (_ IN CASE WHEN r:Commercial THEN [1] ELSE [] END | SET copy:Commercial)
+FOREACH (_ IN CASE WHEN r:Industrial THEN [1] ELSE [] END | SET copy:Industrial)
+FOREACH (_ IN CASE WHEN r:ForSale THEN [1] ELSE [] END | SET copy:ForSale)
+FOREACH (_ IN CASE WHEN r:ForRent THEN [1] ELSE [] END | SET copy:ForRent)
+FOREACH (_ IN CASE WHEN r:Delisted THEN [1] ELSE [] END | SET copy:Delisted)
+DELETE sold
+CREATE (copy)-[:SELLED_BY]->(agency)
+WITH r, copy
+CALL {
+	WITH r, copy
+	MATCH (r)-[:IN]->(place)
+	MERGE (copy)-[:IN]->(place)
+}
+CALL {
+	WITH r, copy
+	MATCH (r)-[:FIRST_PRICE]->(price)
+	CREATE (copy)-[:FIRST_PRICE]->(price)
+}
+CALL {
+	WITH r, copy
+	MATCH (r)-[:LATEST_PRICE]->(price)
+	CREATE (copy)-[:LATEST_PRICE]->(price)
+};
+
+// Same rules as RealEstate.Key: agency and code, or agency and the URL without
+// scheme, fragment and trailing slash. Nodes with neither keep no key.
+MATCH (r:RealEstate)-[:SELLED_BY]->(a:Agency)
+WHERE r.key IS NULL
+WITH r, a, split(split(coalesce(r.url, ""), "://")[-1], "#")[0] AS url
+WITH r, a, CASE WHEN url ENDS WITH "/" THEN left(url, size(url) - 1) ELSE url END AS url
+SET r.key = CASE
+	WHEN trim(coalesce(r.code, "")) <> "" THEN a.normalizedName + ":" + trim(r.code)
+	WHEN url <> "" THEN a.normalizedName + ":url:" + url
+	ELSE null
+END;
+
+CREATE CONSTRAINT real_estate_key IF NOT EXISTS
+FOR (r:RealEstate) REQUIRE r.key IS UNIQUE;
+
+CREATE INDEX real_estate_code IF NOT EXISTS
+FOR (r:RealEstate) ON (r.code);
@@ -0,0 +1,3 @@
+// Unchanged listings are stamped as seen by the URL of their page.
+CREATE INDEX real_estate_url IF NOT EXISTS
+FOR (r:RealEstate) ON (r.url);
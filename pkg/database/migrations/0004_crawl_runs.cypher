@@ -0,0 +1,10 @@
+// Every crawl run has a report node, and the real estates it created and the
+// prices it changed are looked up by its id.
+CREATE CONSTRAINT crawl_run_id IF NOT EXISTS
+FOR (run:CrawlRun) REQUIRE run.id IS UNIQUE;
+
+CREATE INDEX real_estate_first_crawl IF NOT EXISTS
+FOR (r:RealEstate) ON (r.firstCrawlId);
+
+CREATE INDEX price_crawl IF NOT EXISTS
+FOR (p:Price) ON (p.crawlId);
@@ -0,0 +1,23 @@
+// Districts were merged by name alone, so the districts of the same name in
+// different cities were a single node. Each city gets its own district, keyed
+// by the normalized name of the city and the name of the district, along with
+// the real estates of the city that were in the shared one.
+DROP CONSTRAINT district_name IF EXISTS;
+
+MATCH (d:District)-[:IN]->(c:City)
+WHERE d.key IS NULL
+MERGE (own:District {key: c.normalizedName + ":" + d.name})
+ON CREATE SET own.id = randomUUID(), own.name = d.name
+MERGE (own)-[:IN]->(c)
+WITH d, c, own
+MATCH (r:RealEstate)-[in:IN]->(d)
+WHERE (r)-[:IN]->(c)
+DELETE in
+MERGE (r)-[:IN]->(own);
+
+MATCH (d:District)
+WHERE d.key IS NULL
+DETACH DELETE d;
+
+CREATE CONSTRAINT district_key IF NOT EXISTS
+FOR (d:District) REQUIRE d.key IS UNIQUE;
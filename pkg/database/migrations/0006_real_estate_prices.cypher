@@ -0,0 +1,51 @@
+// Migration 0002 linked the copies of a split real estate to the price nodes
+// of the merged one, so their histories share a chain that branches into the
+// prices each of them got since. Mark the shared chains, give every real
+// estate its own clone of the prices leading to its latest ones, and delete
+// the shared chains.
+MATCH (first:Price)<-[:FIRST_PRICE]-(r:RealEstate)
+WITH first, count(r) AS owners
+WHERE owners > 1
+MATCH (first)-[:NEXT*0..]->(price:Price)
+SET price:SharedPrice;
+
+MATCH (r:RealEstate)-[:FIRST_PRICE]->(first:SharedPrice)
+CALL {
+	WITH r, first
+	MATCH path = (first)-[:NEXT*0..]->(:Price)<-[:LATEST_PRICE]-(r)
+	UNWIND nodes(path) AS price
+	WITH r, first, collect(DISTINCT price) AS prices
+	UNWIND prices AS price
+	CREATE (clone:Price)
+	SET clone = properties(price), clone.id = randomUUID()
+	FOREACH (_ IN CASE WHEN price:SalePrice THEN [1] ELSE [] END | SET clone:SalePrice)
+	FOREACH (_ IN CASE WHEN price:RentalPrice THEN [1] ELSE [] END | SET clone:RentalPrice)
+	WITH r, first, collect([price, clone]) AS pairs
+	UNWIND pairs AS pair
+	WITH r, first, pairs, pair[0] AS price, pair[1] AS clone
+	WITH r, first, pairs, price, clone, [(price)-[:NEXT]->(next) | next] AS nexts
+	FOREACH (_ IN CASE WHEN price = first THEN [1] ELSE [] END |
+		CREATE (r)-[:FIRST_PRICE]->(clone)
+	)
+	FOREACH (_ IN CASE WHEN EXISTS { (r)-[:LATEST_PRICE]->(price) } THEN [1] ELSE [] END |
+		CREATE (r)-[:LATEST_PRICE]->(clone)
+	)
+	FOREACH (next IN [p IN pairs WHERE p[0] IN nexts | p[1]] |
+		CREATE (clone)-[:NEXT]->(next)
+	)
+};
+
+MATCH (price:SharedPrice)
+DETACH DELETE price;
+
+// RealEstate.Key lowercases the host of the URL, which migration 0002 did not.
+// A real estate saved since under the lowercase key keeps it, and the old node
+// is left to be delisted.
+MATCH (r:RealEstate)-[:SELLED_BY]->(a:Agency)
+WHERE r.key STARTS WITH a.normalizedName + ":url:"
+WITH r, a, substring(r.key, size(a.normalizedName + ":url:")) AS url
+WITH r, a, url, split(split(url, "/")[0], "?")[0] AS host
+WHERE host <> toLower(host)
+WITH r, a.normalizedName + ":url:" + toLower(host) + substring(url, size(host)) AS key
+WHERE NOT EXISTS { MATCH (other:RealEstate {key: key}) }
+SET r.key = key;
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+//go:embed migrations/*.cypher
+var migrationFiles embed.FS
+
+// migrationName matches files such as 0001_initial_constraints.cypher.
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.cypher$`)
+
+// Migration is a versioned Cypher file applied once to the graph.
+type Migration struct {
+	Version    int
+	Name       string
+	Checksum   string
+	Statements []string
+}
+
+// LoadMigrations reads the migrations of a directory in version order.
+// Statements are separated by a semicolon at the end of a line, and lines
+// starting with // are comments.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	versions := make(map[int]string)
+
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", other, entry.Name(), version)
+		}
+		versions[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+
+		sum := sha256.Sum256(content)
+
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       match[2],
+			Checksum:   hex.EncodeToString(sum[:]),
+			Statements: splitStatements(string(content)),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func splitStatements(content string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+	)
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, statement)
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
+
+// Migrator applies the migrations shipped with the project, tracking each of
+// them as a :Migration node. Migrations are up-only: an applied migration whose
+// file changed is reported instead of being applied again.
+type Migrator struct {
+	driver     neo4j.DriverWithContext
+	logger     *slog.Logger
+	migrations []Migration
+}
+
+// NewMigrator is a constructor that creates a new Migrator with the embedded migrations.
+func NewMigrator(driver neo4j.DriverWithContext, logger *slog.Logger) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		driver:     driver,
+		logger:     logger,
+		migrations: migrations,
+	}, nil
+}
+
+// Pending verifies the applied migrations and returns the ones still to apply.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int]bool)
+	pending := make([]Migration, 0)
+
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+
+		checksum, ok := applied[migration.Version]
+		if !ok {
+			pending = append(pending, migration)
+			continue
+		}
+
+		if checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s changed after being applied (checksum %s, applied %s)",
+				migration.Version, migration.Name, migration.Checksum, checksum)
+		}
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("migration %04d is applied but unknown to this version of baia", version)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate applies every pending migration in version order and returns them.
+func (m *Migrator) Migrate(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		m.logger.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+
+		if err := m.apply(ctx, migration); err != nil {
+			return pending[:i], fmt.Errorf("failed to apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]string, error) {
+	session := m.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
+	defer session.Close(ctx)
+
+	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		result, err := tx.Run(ctx, `MATCH (m:Migration) RETURN m.version AS version, m.checksum AS checksum`, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		records, err := result.Collect(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+
+		applied := make(map[int]string, len(records))
+		for _, record := range records {
+			version, _ := record.Get("version")
+			checksum, _ := record.Get("checksum")
+
+			v, _ := version.(int64)
+			c, _ := checksum.(string)
+			applied[int(v)] = c
+		}
+
+		return applied, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(map[int]string), nil
+}
+
+// apply runs every statement in its own transaction, since Neo4j does not mix
+// schema changes and data writes in one, and then records the migration.
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	session := m.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	for _, statement := range migration.Statements {
+		_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			_, err := tx.Run(ctx, statement, nil)
+			return nil, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute statement %q: %w", statement, err)
+		}
+	}
+
+	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+		_, err := tx.Run(ctx, `
+			CREATE (:Migration {
+				version: $version,
+				name: $name,
+				checksum: $checksum,
+				appliedAt: datetime()
+			})
+		`, map[string]any{
+			"version":  migration.Version,
+			"name":     migration.Name,
+			"checksum": migration.Checksum,
+		})
+		return nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,12 @@
+// Package page describes fetched pages apart from how they are fetched, so
+// the real estates and the collector can share it.
+package page
+
+// Validators tell whether a page changed since it was last fetched.
+type Validators struct {
+	ETag         string
+	LastModified string
+	// ContentHash is the SHA-256 of the body, for sites that send neither
+	// an ETag nor a Last-Modified.
+	ContentHash string
+}